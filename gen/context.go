@@ -0,0 +1,53 @@
+package gen
+
+import "context"
+
+// valueContext keeps all the generation values in a single list of key value pairs. Deriving a valueContext
+// copies the pairs of its parent instead of chaining to it, so lookups do not walk a chain that grows with the
+// nesting depth of the schema. The generator sets a handful of keys, which a list scans faster than a map
+// and copies with a single allocation.
+type valueContext struct {
+	context.Context
+	values []keyValue
+}
+
+type keyValue struct {
+	key   interface{}
+	value interface{}
+}
+
+func (vc *valueContext) Value(key interface{}) interface{} {
+	for _, kv := range vc.values {
+		if kv.key == key {
+			return kv.value
+		}
+	}
+	return vc.Context.Value(key)
+}
+
+// withValues derives a context from ctx that also holds the given key value pairs.
+func withValues(ctx context.Context, keyValues ...interface{}) context.Context {
+	parent := ctx
+	var inherited []keyValue
+	if vc, ok := ctx.(*valueContext); ok {
+		parent = vc.Context
+		inherited = vc.values
+	}
+	values := make([]keyValue, len(inherited), len(inherited)+len(keyValues)/2)
+	copy(values, inherited)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		values = set(values, keyValues[i], keyValues[i+1])
+	}
+	return &valueContext{Context: parent, values: values}
+}
+
+// set replaces the value of key in values, or appends it if the key is not present.
+func set(values []keyValue, key, value interface{}) []keyValue {
+	for i := range values {
+		if values[i].key == key {
+			values[i].value = value
+			return values
+		}
+	}
+	return append(values, keyValue{key: key, value: value})
+}
@@ -0,0 +1,71 @@
+package gen
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// contextKeys are the keys of the values set by Generate.
+var contextKeys = []string{XmlPrefixes, IsArray, Fields, DocPath, BasePath, Warnings, Names, Documents, Visited,
+	RequiredFields, XmlWrapper, AnyOf, AllOf, EnclosingType}
+
+// nestedDoc returns a document whose schema nests depth objects.
+func nestedDoc(depth int) string {
+	var sb strings.Builder
+	sb.WriteString("components:\n  schemas:\n    Root:\n")
+	indent := "      "
+	for i := 0; i < depth; i++ {
+		sb.WriteString(indent + "type: object\n" + indent + "properties:\n")
+		sb.WriteString(indent + "  name: {type: string}\n")
+		sb.WriteString(indent + "  child:\n")
+		indent += "    "
+	}
+	sb.WriteString(indent + "type: string\n")
+	return sb.String()
+}
+
+func BenchmarkHandleSchema(b *testing.B) {
+	doc := nestedDoc(50)
+	for i := 0; i < b.N; i++ {
+		sg := NewSchemaGen()
+		if err := sg.AddFromReader(strings.NewReader(doc), "nested.yaml", FormatYAML); err != nil {
+			b.Fatal(err)
+		}
+		if err := sg.Generate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkDerive derives a context per nesting level, as handleObject does, and looks all the values up at
+// each level.
+func benchmarkDerive(b *testing.B, derive func(ctx context.Context, keyValues ...interface{}) context.Context) {
+	for i := 0; i < b.N; i++ {
+		ctx := context.Background()
+		for _, k := range contextKeys {
+			ctx = derive(ctx, k, k)
+		}
+		for depth := 0; depth < 50; depth++ {
+			ctx = derive(ctx, Fields, depth, RequiredFields, depth, IsArray, false, XmlWrapper, "", AnyOf, false, AllOf, false)
+			for _, k := range contextKeys {
+				if ctx.Value(k) == nil {
+					b.Fatalf("value %s not found", k)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkWithValues(b *testing.B) {
+	benchmarkDerive(b, withValues)
+}
+
+func BenchmarkContextWithValue(b *testing.B) {
+	benchmarkDerive(b, func(ctx context.Context, keyValues ...interface{}) context.Context {
+		for i := 0; i+1 < len(keyValues); i += 2 {
+			ctx = context.WithValue(ctx, keyValues[i], keyValues[i+1])
+		}
+		return ctx
+	})
+}
@@ -0,0 +1,33 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+)
+
+// Diagnostic is a non fatal problem found in the specification while generating.
+type Diagnostic struct {
+	Field   string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return d.Field + ": " + d.Message
+}
+
+// Diagnostics collects the warnings reported while generating.
+type Diagnostics struct {
+	Warnings []Diagnostic
+}
+
+// Warn records a warning for the given field.
+func (d *Diagnostics) Warn(field, format string, args ...interface{}) {
+	d.Warnings = append(d.Warnings, Diagnostic{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// warn records a warning on the Diagnostics of the context if one is present.
+func warn(ctx context.Context, field, format string, args ...interface{}) {
+	if d, ok := ctx.Value(Warnings).(*Diagnostics); ok && d != nil {
+		d.Warn(field, format, args...)
+	}
+}
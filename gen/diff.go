@@ -0,0 +1,180 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChangeKind describes how a type or field changed between two versions of a specification.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a single difference between two versions of a specification.
+type Change struct {
+	Kind     ChangeKind
+	Type     string // Name of the schema
+	Field    string // Dotted path of the member within the schema, empty for the schema itself
+	Message  string
+	Breaking bool
+}
+
+func (c Change) String() string {
+	path := c.Type
+	if c.Field != "" {
+		path += "." + c.Field
+	}
+	return fmt.Sprintf("%s %s: %s", c.Kind, path, c.Message)
+}
+
+// SchemaDiff lists the changes between two versions of a specification.
+type SchemaDiff struct {
+	Changes []Change
+}
+
+// Breaking reports whether any of the changes is breaking.
+func (sd *SchemaDiff) Breaking() bool {
+	for _, c := range sd.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares the generated field models of two versions of a specification and reports the added, removed
+// and changed types, fields and enum values. Removing a type, a field or an enum value, changing a field type and
+// making a field required are reported as breaking. Both SchemaGen are expected to have been generated.
+func Diff(before, after SchemaGen) (*SchemaDiff, error) {
+	sd := &SchemaDiff{}
+	for _, name := range sortedSchemaNames(before, after) {
+		beforeInfo, inBefore := before.SchemaInfos[name]
+		afterInfo, inAfter := after.SchemaInfos[name]
+		switch {
+		case !inAfter:
+			sd.add(Change{Kind: Removed, Type: name, Message: "type removed", Breaking: true})
+		case !inBefore:
+			sd.add(Change{Kind: Added, Type: name, Message: "type added"})
+		default:
+			beforeField, ok := beforeInfo.Fields[name]
+			if !ok {
+				return nil, fmt.Errorf("schema %s of the specification before has not been generated", name)
+			}
+			afterField, ok := afterInfo.Fields[name]
+			if !ok {
+				return nil, fmt.Errorf("schema %s of the specification after has not been generated", name)
+			}
+			sd.compare(name, "", beforeField, afterField)
+		}
+	}
+	return sd, nil
+}
+
+func (sd *SchemaDiff) add(c Change) {
+	sd.Changes = append(sd.Changes, c)
+}
+
+func (sd *SchemaDiff) compare(typeName, path string, beforeValue, afterValue interface{}) {
+	beforeField := fieldOf(beforeValue)
+	afterField := fieldOf(afterValue)
+	if beforeField.Type != afterField.Type {
+		sd.add(Change{Kind: Changed, Type: typeName, Field: path, Breaking: true,
+			Message: fmt.Sprintf("type changed from %s to %s", beforeField.Type, afterField.Type)})
+		return
+	}
+	if beforeField.IsArray != afterField.IsArray {
+		sd.add(Change{Kind: Changed, Type: typeName, Field: path, Breaking: true,
+			Message: fmt.Sprintf("array changed from %t to %t", beforeField.IsArray, afterField.IsArray)})
+	}
+	if beforeField.Required != afterField.Required {
+		sd.add(Change{Kind: Changed, Type: typeName, Field: path, Breaking: afterField.Required,
+			Message: fmt.Sprintf("required changed from %t to %t", beforeField.Required, afterField.Required)})
+	}
+
+	switch b := beforeValue.(type) {
+	case RefField:
+		if a := afterValue.(RefField); b.Reference != a.Reference {
+			sd.add(Change{Kind: Changed, Type: typeName, Field: path, Breaking: true,
+				Message: fmt.Sprintf("reference changed from %s to %s", b.Reference, a.Reference)})
+		}
+	case EnumField:
+		sd.compareValues(typeName, path, b.Values, afterValue.(EnumField).Values)
+	case ObjectField:
+		sd.compareMembers(typeName, path, b.Members, afterValue.(ObjectField).Members)
+	}
+}
+
+// compareValues reports the values removed from and added to an enum.
+func (sd *SchemaDiff) compareValues(typeName, path string, beforeValues, afterValues []interface{}) {
+	for _, v := range beforeValues {
+		if !containsValue(afterValues, v) {
+			sd.add(Change{Kind: Removed, Type: typeName, Field: path, Breaking: true,
+				Message: fmt.Sprintf("enum value %v removed", v)})
+		}
+	}
+	for _, v := range afterValues {
+		if !containsValue(beforeValues, v) {
+			sd.add(Change{Kind: Added, Type: typeName, Field: path,
+				Message: fmt.Sprintf("enum value %v added", v)})
+		}
+	}
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (sd *SchemaDiff) compareMembers(typeName, path string, beforeMembers, afterMembers map[string]interface{}) {
+	var names []string
+	for k := range beforeMembers {
+		names = append(names, k)
+	}
+	for k := range afterMembers {
+		if _, ok := beforeMembers[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		memberPath := k
+		if path != "" {
+			memberPath = path + "." + k
+		}
+		beforeMember, inBefore := beforeMembers[k]
+		afterMember, inAfter := afterMembers[k]
+		switch {
+		case !inAfter:
+			sd.add(Change{Kind: Removed, Type: typeName, Field: memberPath, Breaking: true,
+				Message: fmt.Sprintf("field removed (required: %t)", fieldOf(beforeMember).Required)})
+		case !inBefore:
+			required := fieldOf(afterMember).Required
+			sd.add(Change{Kind: Added, Type: typeName, Field: memberPath, Breaking: required,
+				Message: fmt.Sprintf("field added (required: %t)", required)})
+		default:
+			sd.compare(typeName, memberPath, beforeMember, afterMember)
+		}
+	}
+}
+
+func sortedSchemaNames(before, after SchemaGen) []string {
+	var names []string
+	for k := range before.SchemaInfos {
+		names = append(names, k)
+	}
+	for k := range after.SchemaInfos {
+		if _, ok := before.SchemaInfos[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
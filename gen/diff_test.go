@@ -0,0 +1,97 @@
+package gen
+
+import "testing"
+
+const diffBefore = `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        tag: {type: string}
+        age: {type: integer}
+        status: {type: string, enum: [available, sold]}
+    Owner:
+      type: object
+`
+
+func TestDiff(t *testing.T) {
+	before := generate(t, NewSchemaGen(), "pets.yaml", diffBefore)
+	after := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [tag]
+      properties:
+        tag: {type: string}
+        age: {type: string}
+        status: {type: string, enum: [available, pending]}
+        color: {type: string}
+    Store:
+      type: object
+`)
+	sd, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Kind: Removed, Type: "Owner", Message: "type removed", Breaking: true},
+		{Kind: Changed, Type: "Pet", Field: "age", Message: "type changed from int64 to string", Breaking: true},
+		{Kind: Added, Type: "Pet", Field: "color", Message: "field added (required: false)"},
+		{Kind: Removed, Type: "Pet", Field: "name", Message: "field removed (required: true)", Breaking: true},
+		{Kind: Removed, Type: "Pet", Field: "status", Message: "enum value sold removed", Breaking: true},
+		{Kind: Added, Type: "Pet", Field: "status", Message: "enum value pending added"},
+		{Kind: Changed, Type: "Pet", Field: "tag", Message: "required changed from false to true", Breaking: true},
+		{Kind: Added, Type: "Store", Message: "type added"},
+	}
+	if len(sd.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %v", len(want), sd.Changes)
+	}
+	for i, c := range sd.Changes {
+		if c != want[i] {
+			t.Errorf("change %d: expected %v (breaking %t), got %v (breaking %t)", i, want[i], want[i].Breaking, c, c.Breaking)
+		}
+	}
+	if !sd.Breaking() {
+		t.Error("diff not reported as breaking")
+	}
+}
+
+func TestDiffWithoutChanges(t *testing.T) {
+	sd, err := Diff(generate(t, NewSchemaGen(), "pets.yaml", diffBefore), generate(t, NewSchemaGen(), "pets.yaml", diffBefore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sd.Changes) > 0 || sd.Breaking() {
+		t.Errorf("expected no changes, got %v", sd.Changes)
+	}
+}
+
+func TestDiffAddedOptionalIsNotBreaking(t *testing.T) {
+	before := generate(t, NewSchemaGen(), "pets.yaml", diffBefore)
+	after := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        tag: {type: string}
+        age: {type: integer}
+        status: {type: string, enum: [available, sold, pending]}
+        color: {type: string}
+    Owner:
+      type: object
+`)
+	sd, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sd.Changes) != 2 || sd.Breaking() {
+		t.Errorf("expected two non breaking additions, got %v", sd.Changes)
+	}
+}
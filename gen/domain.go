@@ -0,0 +1,159 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ModelSuffix is appended to the name of a wire type to name its domain type.
+const ModelSuffix = "Model"
+
+// declareModels declares the domain type of each struct, and of each type holding structs through its elements,
+// right after the wire type it is converted to and from.
+func (r *renderer) declareModels() error {
+	c := converter{models: r.modelTypes()}
+	var types []renderType
+	for _, rt := range r.types {
+		types = append(types, rt)
+		if !c.models[rt.Name] {
+			continue
+		}
+		name := rt.Name + ModelSuffix
+		if r.declared[name] {
+			return fmt.Errorf("schema %s: domain type %s of %s clashes with another declaration, rename %s with %s",
+				rt.Schema, name, rt.Name, rt.Name, XGoName)
+		}
+		r.declared[name] = true
+		types = append(types, c.model(rt))
+	}
+	r.types = types
+	return nil
+}
+
+// modelTypes returns the names of the declared types getting a domain type: the structs and the types holding
+// structs through their elements.
+func (r *renderer) modelTypes() map[string]bool {
+	models := make(map[string]bool)
+	var holds func(t *goType, onPath map[string]bool) bool
+	holds = func(t *goType, onPath map[string]bool) bool {
+		if t.Elem != nil {
+			return holds(t.Elem, onPath)
+		}
+		rt := r.typeNamed(t.Name)
+		if rt == nil || onPath[t.Name] {
+			return false
+		}
+		onPath[t.Name] = true
+		defer delete(onPath, t.Name)
+		return rt.Underlying == "struct{}" || holds(parseType(rt.Underlying), onPath)
+	}
+	for _, rt := range r.types {
+		if holds(&goType{Name: rt.Name}, make(map[string]bool)) {
+			models[rt.Name] = true
+		}
+	}
+	return models
+}
+
+// converter renders the conversions between the wire types and their domain types.
+type converter struct {
+	models map[string]bool // Names of the wire types having a domain type
+}
+
+// modelName returns the name of the domain type of the named type, the name itself if the type is shared.
+func (c converter) modelName(name string) string {
+	if c.models[name] {
+		return name + ModelSuffix
+	}
+	return name
+}
+
+// holds reports whether the type, or one of its elements, has a domain type.
+func (c converter) holds(t *goType) bool {
+	if t.Elem != nil {
+		return c.holds(t.Elem)
+	}
+	return c.models[t.Name]
+}
+
+// model returns the domain type of the wire type, declaring the same fields without their tags, along with its
+// conversion methods.
+func (c converter) model(rt renderType) renderType {
+	name := c.modelName(rt.Name)
+	m := renderType{Name: name, Schema: rt.Schema, Underlying: parseType(rt.Underlying).format(c.modelName),
+		Doc: []string{name + " is the domain type of " + rt.Name + ", converted to and from it by ToWire and FromWire."}}
+	toWire := []string{"var w " + rt.Name}
+	var fromWire []string
+	if rt.Underlying == "struct{}" {
+		for _, f := range rt.Fields {
+			t := parseType(f.Type)
+			mf := renderField{Name: f.Name, Type: t.format(c.modelName), Doc: f.Doc, Comment: f.Comment, Embedded: f.Embedded}
+			m.Fields = append(m.Fields, mf)
+			wire, model := "w."+selector(f), "m."+selector(mf)
+			toWire = append(toWire, c.convert(wire, model, t, true, 1)...)
+			fromWire = append(fromWire, c.convert(model, wire, t, false, 1)...)
+		}
+	} else {
+		t := parseType(rt.Underlying)
+		toWire = append(toWire, c.convert("w", "m", t, true, 1)...)
+		fromWire = append(append([]string{"var v " + name}, c.convert("v", "w", t, false, 1)...), "*m = v")
+	}
+	m.Methods = []renderMethod{
+		{
+			Doc:       []string{"ToWire converts the " + name + " to its wire type " + rt.Name + "."},
+			Signature: "(m " + name + ") ToWire() " + rt.Name,
+			Body:      append(toWire, "return w"),
+		},
+		{
+			Doc:       []string{"FromWire sets the " + name + " from its wire type " + rt.Name + "."},
+			Signature: "(m *" + name + ") FromWire(w " + rt.Name + ")",
+			Body:      fromWire,
+		},
+	}
+	return m
+}
+
+// selector returns the name the field is selected by, the name of the type for an embedded field.
+func selector(f renderField) string {
+	if f.Embedded {
+		return strings.TrimPrefix(f.Type, "*")
+	}
+	return f.Name
+}
+
+// convert returns the statements setting dst from src, of the wire type t when converting toWire and of its
+// domain type otherwise. The pointers, slices and maps holding domain types are copied, depth numbers the
+// variables of the nested copies.
+func (c converter) convert(dst, src string, t *goType, toWire bool, depth int) []string {
+	if !c.holds(t) {
+		return []string{dst + " = " + src}
+	}
+	typ := t.format(c.modelName)
+	if toWire {
+		typ = t.format(func(name string) string { return name })
+	}
+	n := strconv.Itoa(depth)
+	switch t.Prefix {
+	case "*":
+		elemType := strings.TrimPrefix(typ, "*")
+		lines := []string{"if " + src + " != nil {", "e" + n + " := *" + src, "var v" + n + " " + elemType}
+		lines = append(lines, c.convert("v"+n, "e"+n, t.Elem, toWire, depth+1)...)
+		return append(lines, dst+" = &v"+n, "}")
+	case "[]":
+		lines := []string{"if " + src + " != nil {", dst + " = make(" + typ + ", len(" + src + "))",
+			"for i" + n + ", e" + n + " := range " + src + " {"}
+		lines = append(lines, c.convert(dst+"[i"+n+"]", "e"+n, t.Elem, toWire, depth+1)...)
+		return append(lines, "}", "}")
+	case "map[string]":
+		elemType := strings.TrimPrefix(typ, "map[string]")
+		lines := []string{"if " + src + " != nil {", dst + " = make(" + typ + ", len(" + src + "))",
+			"for k" + n + ", e" + n + " := range " + src + " {", "var v" + n + " " + elemType}
+		lines = append(lines, c.convert("v"+n, "e"+n, t.Elem, toWire, depth+1)...)
+		return append(lines, dst+"[k"+n+"] = v"+n, "}", "}")
+	}
+	if toWire {
+		return []string{dst + " = " + src + ".ToWire()"}
+	}
+	return []string{dst + ".FromWire(" + src + ")"}
+}
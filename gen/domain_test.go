@@ -0,0 +1,98 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDomainModelsRoundTrip(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.DomainModels = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Named:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Named'
+        - type: object
+          properties:
+            status: {type: string, enum: [available, sold]}
+            owner:
+              type: object
+              properties:
+                email: {type: string}
+            friends:
+              type: array
+              items: {$ref: '#/components/schemas/Pet'}
+            best:
+              $ref: '#/components/schemas/Pet'
+            toys:
+              type: object
+              additionalProperties:
+                type: array
+                items: {$ref: '#/components/schemas/Named'}
+            born: {type: string, format: date-time}
+    Pets:
+      type: array
+      items: {$ref: '#/components/schemas/Pet'}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "type PetModel struct", "NamedModel\n", "Friends []PetModel\n",
+		"Best *PetModel\n", "Toys PetToysModel\n", "type PetToysModel map[string][]NamedModel", "type PetsModel []PetModel",
+		"Status *PetStatus\n", "Born *time.Time\n", "func (m PetModel) ToWire() Pet {", "func (m *PetModel) FromWire(w Pet) {")
+	if strings.Contains(source, "PetStatusModel") {
+		t.Errorf("expected the enum to be shared\n%s", source)
+	}
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+func main() {
+	email, status := "joe@example.com", PetStatusSold
+	born := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	best := PetModel{NamedModel: NamedModel{Name: "rex"}}
+	m := PetModel{
+		NamedModel: NamedModel{Name: "fido"},
+		Status:     &status,
+		Owner:      PetOwnerModel{Email: &email},
+		Friends:    []PetModel{best, {NamedModel: NamedModel{Name: "max"}}},
+		Best:       &best,
+		Toys:       PetToysModel{"balls": {{Name: "red"}, {Name: "blue"}}, "none": nil},
+		Born:       &born,
+	}
+	b, err := json.Marshal(m.ToWire())
+	if err != nil {
+		panic(err)
+	}
+	var w Pet
+	if err := json.Unmarshal(b, &w); err != nil {
+		panic(err)
+	}
+	var back PetModel
+	back.FromWire(w)
+	fmt.Println(reflect.DeepEqual(m, back))
+
+	pets := PetsModel{m, best}
+	var fromWire PetsModel
+	fromWire.FromWire(pets.ToWire())
+	fmt.Println(reflect.DeepEqual(pets, fromWire))
+	fmt.Println(PetsModel(nil).ToWire() == nil)
+}
+`,
+	})
+	if out != "true\ntrue\ntrue\n" {
+		t.Errorf("expected the models to round trip, got %s\n%s", out, source)
+	}
+}
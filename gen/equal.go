@@ -0,0 +1,130 @@
+package gen
+
+import (
+	"strconv"
+	"strings"
+)
+
+// comparableTypes are the predeclared types compared with ==.
+var comparableTypes = map[string]bool{
+	"string": true, "bool": true, "byte": true, "rune": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// declareEqual adds an Equal method to each declared type that cannot be compared with ==: the structs, the maps
+// and slices, and the types holding times, raw JSON or free-form values.
+func (r *renderer) declareEqual() {
+	e := equality{r: r, equal: make(map[string]bool)}
+	for i := range r.types {
+		rt := &r.types[i]
+		if !e.hasEqual(rt.Name, make(map[string]bool)) {
+			continue
+		}
+		r.schema = rt.Schema
+		var body []string
+		if rt.Underlying == "struct{}" {
+			for _, f := range rt.Fields {
+				body = append(body, e.compare("v."+selector(f), "other."+selector(f), parseType(f.Type), 1)...)
+			}
+			body = append(body, "return true")
+		} else {
+			body = e.compareUnderlying(parseType(rt.Underlying))
+		}
+		rt.Methods = append(rt.Methods, renderMethod{
+			Doc:       []string{"Equal reports whether the " + rt.Name + " holds the same values as other, nil only being equal to nil."},
+			Signature: "(v *" + rt.Name + ") Equal(other *" + rt.Name + ") bool",
+			Body:      append([]string{"if v == nil || other == nil {", "return v == other", "}"}, body...),
+		})
+	}
+}
+
+// equality renders the comparisons of the Equal methods.
+type equality struct {
+	r     *renderer
+	equal map[string]bool // Whether the declared types have an Equal method, by name
+}
+
+// hasEqual reports whether the declared type gets an Equal method.
+func (e equality) hasEqual(name string, onPath map[string]bool) bool {
+	if v, ok := e.equal[name]; ok {
+		return v
+	}
+	rt := e.r.typeNamed(name)
+	if rt == nil || onPath[name] {
+		return false
+	}
+	onPath[name] = true
+	t := parseType(rt.Underlying)
+	v := rt.Underlying == "struct{}" || t.Elem != nil || !comparableTypes[t.Name] && e.r.typeNamed(t.Name) == nil ||
+		e.hasEqual(t.Name, onPath)
+	e.equal[name] = v
+	return v
+}
+
+// compareUnderlying returns the statements comparing *v and *other, of a named type with the underlying type t.
+func (e equality) compareUnderlying(t *goType) []string {
+	switch {
+	case t.Elem != nil:
+		return append(e.compare("*v", "*other", t, 1), "return true")
+	case e.hasEqual(t.Name, make(map[string]bool)):
+		return []string{"return (*" + t.Name + ")(v).Equal((*" + t.Name + ")(other))"}
+	case t.Name == "time.Time":
+		return []string{"return time.Time(*v).Equal(time.Time(*other))"}
+	}
+	return append(e.compare("*v", "*other", t, 1), "return true")
+}
+
+// compare returns the statements returning false when a and b, of type t, differ. Maps and slices are equal when
+// both are nil or hold equal elements. Free-form values are compared with reflect.DeepEqual. depth numbers the
+// variables of the nested comparisons.
+func (e equality) compare(a, b string, t *goType, depth int) []string {
+	n := strconv.Itoa(depth)
+	// Operands of the selectors and index expressions
+	pa, pb := a, b
+	if strings.HasPrefix(a, "*") {
+		pa, pb = "("+a+")", "("+b+")"
+	}
+	differ := func(cond string) []string {
+		return []string{"if " + cond + " {", "return false", "}"}
+	}
+	switch t.Prefix {
+	case "*":
+		if t.Elem.Elem == nil && e.hasEqual(t.Elem.Name, make(map[string]bool)) {
+			return differ("!" + pa + ".Equal(" + b + ")")
+		}
+		lines := differ("(" + a + " == nil) != (" + b + " == nil)")
+		lines = append(lines, "if "+a+" != nil {")
+		lines = append(lines, e.compare("*"+pa, "*"+pb, t.Elem, depth+1)...)
+		return append(lines, "}")
+	case "[]":
+		if t.Elem.Elem == nil && (t.Elem.Name == "byte" || t.Elem.Name == "uint8") {
+			e.r.addImport("bytes")
+			return differ("(" + a + " == nil) != (" + b + " == nil) || !bytes.Equal(" + a + ", " + b + ")")
+		}
+		lines := differ("(" + a + " == nil) != (" + b + " == nil) || len(" + a + ") != len(" + b + ")")
+		lines = append(lines, "for i"+n+" := range "+a+" {")
+		lines = append(lines, e.compare(pa+"[i"+n+"]", pb+"[i"+n+"]", t.Elem, depth+1)...)
+		return append(lines, "}")
+	case "map[string]":
+		lines := differ("(" + a + " == nil) != (" + b + " == nil) || len(" + a + ") != len(" + b + ")")
+		lines = append(lines, "for k"+n+", a"+n+" := range "+a+" {", "b"+n+", ok := "+pb+"[k"+n+"]")
+		lines = append(lines, differ("!ok")...)
+		lines = append(lines, e.compare("a"+n, "b"+n, t.Elem, depth+1)...)
+		return append(lines, "}")
+	}
+	switch {
+	case e.hasEqual(t.Name, make(map[string]bool)):
+		return differ("!" + pa + ".Equal(&" + pb + ")")
+	case t.Name == "time.Time":
+		return differ("!" + pa + ".Equal(" + b + ")")
+	case t.Name == "json.RawMessage":
+		e.r.addImport("bytes")
+		return differ("(" + a + " == nil) != (" + b + " == nil) || !bytes.Equal(" + a + ", " + b + ")")
+	case t.Name == "interface{}":
+		e.r.addImport("reflect")
+		return differ("!reflect.DeepEqual(" + a + ", " + b + ")")
+	}
+	return differ(a + " != " + b)
+}
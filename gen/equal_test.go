@@ -0,0 +1,115 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEqualMethods(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.EqualMethods = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        equal: {type: string}
+        age: {type: integer}
+        tags:
+          type: array
+          items: {type: string}
+        best:
+          $ref: '#/components/schemas/Pet'
+        friends:
+          type: array
+          items: {$ref: '#/components/schemas/Pet'}
+        toys:
+          type: object
+          additionalProperties:
+            type: array
+            items: {type: integer}
+        born: {type: string, format: date-time}
+        photo: {type: string, format: byte}
+        extra: {}
+    Pets:
+      type: array
+      items: {$ref: '#/components/schemas/Pet'}
+    Status:
+      type: string
+      enum: [available, sold]
+`)
+	source := render(t, sg)
+	assertContains(t, source, "func (v *Pet) Equal(other *Pet) bool {", "func (v *Pets) Equal(other *Pets) bool {",
+		"Equal2 ")
+	if strings.Contains(source, "func (v *Status) Equal") {
+		t.Errorf("expected no Equal method on a type comparable with ==\n%s", source)
+	}
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func pet() Pet {
+	age := int64(3)
+	born := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	return Pet{
+		Name:    "fido",
+		Age:     &age,
+		Tags:    []string{"good"},
+		Best:    &Pet{Name: "rex"},
+		Friends: []Pet{{Name: "max"}},
+		Toys:    map[string][]int64{"balls": {1, 2}},
+		Born:    &born,
+		Photo:   []byte{1},
+		Extra:   map[string]interface{}{"a": 1.0},
+	}
+}
+
+func main() {
+	a, b := pet(), pet()
+	fmt.Println(a.Equal(&b))
+	born := b.Born.In(time.FixedZone("X", 3600))
+	b.Born = &born
+	fmt.Println(a.Equal(&b))
+
+	changes := []func(p *Pet){
+		func(p *Pet) { p.Name = "rex" },
+		func(p *Pet) { *p.Age = 4 },
+		func(p *Pet) { p.Age = nil },
+		func(p *Pet) { p.Tags = nil },
+		func(p *Pet) { p.Tags = append(p.Tags, "bad") },
+		func(p *Pet) { p.Best = nil },
+		func(p *Pet) { p.Best.Name = "max" },
+		func(p *Pet) { p.Friends[0].Best = &Pet{} },
+		func(p *Pet) { p.Toys["balls"][1] = 3 },
+		func(p *Pet) { p.Toys = map[string][]int64{"dolls": {1, 2}} },
+		func(p *Pet) { p.Born = nil },
+		func(p *Pet) { p.Photo = []byte{2} },
+		func(p *Pet) { p.Extra = map[string]interface{}{"a": 2.0} },
+	}
+	for i, change := range changes {
+		c := pet()
+		change(&c)
+		if a.Equal(&c) || c.Equal(&a) {
+			fmt.Println("equal after change", i)
+		}
+	}
+
+	var nilPet *Pet
+	fmt.Println(nilPet.Equal(nil), nilPet.Equal(&a), a.Equal(nil))
+	pets, same := Pets{a, pet()}, Pets{pet(), pet()}
+	fmt.Println(pets.Equal(&same), pets.Equal(&Pets{a}), (&Pets{}).Equal(&Pets{}), (&Pets{}).Equal(new(Pets)))
+}
+`,
+	})
+	if out != "true\ntrue\ntrue false false\ntrue false true false\n" {
+		t.Errorf("unexpected comparisons\n%s\n%s", out, source)
+	}
+}
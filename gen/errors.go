@@ -0,0 +1,15 @@
+package gen
+
+// FieldError reports a failure to generate a field of a schema.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (fe *FieldError) Error() string {
+	return "field " + fe.Field + ": " + fe.Err.Error()
+}
+
+func (fe *FieldError) Unwrap() error {
+	return fe.Err
+}
@@ -0,0 +1,250 @@
+package gen
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoExample renders the example of the schema name as a Go expression of the type Render generates for it.
+// Field level examples take precedence over the example of the enclosing schema and defaults, or the only
+// value allowed, are used for the fields left without an example. Fields referencing another schema are left
+// unset. The schema is expected to have been generated.
+func (sg SchemaGen) GoExample(name string) (string, error) {
+	si, ok := sg.SchemaInfos[name]
+	if !ok {
+		return "", fmt.Errorf("schema %s is not registered", name)
+	}
+	v, ok := si.Fields[name]
+	if !ok {
+		return "", fmt.Errorf("schema %s has not been generated", name)
+	}
+	r := sg.newRenderer()
+	r.schema = name
+	literal, err := r.goValue(v, nil)
+	if err != nil {
+		return "", err
+	}
+	if literal == "" {
+		return "", fmt.Errorf("schema %s has no example", name)
+	}
+	if _, ok := v.(ObjectField); !ok {
+		// Converted to the named type declared for the schema
+		literal = fieldOf(v).Name + "(" + literal + ")"
+	}
+	return literal, nil
+}
+
+// goValue renders value as a literal of the field. An empty string is returned when there is no value to render.
+func (r *renderer) goValue(v interface{}, value interface{}) (string, error) {
+	f := fieldOf(v)
+	if _, ok := v.(RefField); ok || !f.IsArray {
+		return r.goElem(v, value)
+	}
+	if value == nil && f.Example != nil {
+		// The field of an array carries the example of its items
+		value = []interface{}{f.Example}
+	}
+	if value == nil {
+		return "", nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return "", exampleError(f, "example %v is not an array", value)
+	}
+	elems := make([]string, 0, len(items))
+	for _, item := range items {
+		elem, err := r.goElem(v, item)
+		if err != nil {
+			return "", err
+		}
+		if elem == "" {
+			return "", exampleError(f, "example %v has an item without a value", value)
+		}
+		elems = append(elems, elem)
+	}
+	return r.typeOf(v) + "{" + strings.Join(elems, ", ") + "}", nil
+}
+
+// goElem renders a single value of the field, ignoring whether the field is an array. Strings, numbers,
+// booleans and enums are rendered as untyped constants.
+func (r *renderer) goElem(v interface{}, value interface{}) (string, error) {
+	f := fieldOf(v)
+	if f.Example != nil && !f.IsArray {
+		value = f.Example
+	}
+	switch t := v.(type) {
+	case StringField:
+		if value == nil && t.Default != nil {
+			value = *t.Default
+		}
+		literal, err := goString(f, value)
+		if literal == "" || err != nil || t.Format == nil {
+			return literal, err
+		}
+		return goFormatted(f, *t.Format, value.(string))
+	case NumberField:
+		if value == nil && t.Default != nil {
+			value = *t.Default
+		}
+		if value == nil && t.Const != nil {
+			value = *t.Const
+		}
+		return goNumber(f, t.Type, value)
+	case BooleanField:
+		if value == nil && t.Default != nil {
+			value = *t.Default
+		}
+		if value == nil {
+			return "", nil
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return "", exampleError(f, "example %v is not a boolean", value)
+		}
+		return strconv.FormatBool(b), nil
+	case EnumField:
+		if t.BaseType == "string" {
+			return goString(f, value)
+		}
+		return goNumber(f, t.BaseType, value)
+	case ObjectField:
+		return r.goStruct(t, value)
+	case Field:
+		if value == nil {
+			return "", nil
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", exampleError(f, "%v", err)
+		}
+		if t.Type == "json.RawMessage" {
+			return "json.RawMessage(" + strconv.Quote(string(b)) + ")", nil
+		}
+		// interface{} holds the decoded JSON value, only scalars have a direct literal
+		switch x := value.(type) {
+		case string:
+			return strconv.Quote(x), nil
+		case float64, bool:
+			return string(b), nil
+		}
+		return "", exampleError(f, "example %s has no Go literal for %s", b, t.Type)
+	}
+	return "", nil
+}
+
+func (r *renderer) goStruct(f ObjectField, value interface{}) (string, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok && value != nil {
+		return "", exampleError(f.Field, "example %v is not an object", value)
+	}
+	f, _ = r.flatten(f)
+	var names []string
+	for k := range f.Members {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	fieldNames := r.fieldNames(f)
+	var members []string
+	for _, k := range names {
+		member := f.Members[k]
+		literal, err := r.goValue(member, m[k])
+		if err != nil {
+			return "", err
+		}
+		if literal == "" {
+			continue
+		}
+		if typ := r.memberType(member); strings.HasPrefix(typ, "*") {
+			literal = pointerTo(member, typ, literal)
+		}
+		members = append(members, fieldNames[k]+": "+literal)
+	}
+	if len(members) == 0 && value == nil {
+		return "", nil
+	}
+	return f.Type + "{" + strings.Join(members, ", ") + "}", nil
+}
+
+// pointerTo returns an expression of the pointer type typ to the value of the literal. Constants are converted
+// to the type pointed to as they would otherwise take their default type.
+func pointerTo(v interface{}, typ, literal string) string {
+	if _, ok := v.(ObjectField); ok {
+		return "&" + literal
+	}
+	if isConstant(literal) {
+		literal = typ[1:] + "(" + literal + ")"
+	}
+	return "func() " + typ + " { v := " + literal + "; return &v }()"
+}
+
+// isConstant reports whether the literal is an untyped string, number or boolean constant.
+func isConstant(literal string) bool {
+	if literal == "true" || literal == "false" || strings.HasPrefix(literal, `"`) {
+		return true
+	}
+	_, err := strconv.ParseFloat(literal, 64)
+	return err == nil
+}
+
+func goString(f Field, value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", exampleError(f, "example %v is not a string", value)
+	}
+	return strconv.Quote(s), nil
+}
+
+// goFormatted renders the string s of the given format as a value of the Go type of the format, time.Time
+// for dates and []byte for byte and binary. Strings of the other formats are kept as is.
+func goFormatted(f Field, format, s string) (string, error) {
+	switch format {
+	case "date", "date-time":
+		layout := time.RFC3339Nano
+		if format == "date" {
+			layout = "2006-01-02"
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return "", exampleError(f, "example %q is not a valid %s", s, format)
+		}
+		t = t.UTC()
+		return fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, %d, time.UTC)",
+			t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond()), nil
+	case "byte":
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", exampleError(f, "example %q is not base64 encoded", s)
+		}
+		return "[]byte(" + strconv.Quote(string(b)) + ")", nil
+	case "binary":
+		return "[]byte(" + strconv.Quote(s) + ")", nil
+	}
+	return strconv.Quote(s), nil
+}
+
+func goNumber(f Field, goType string, value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	n, ok := value.(float64)
+	if !ok {
+		return "", exampleError(f, "example %v is not a number", value)
+	}
+	if !strings.HasPrefix(goType, "float") && n != math.Trunc(n) {
+		return "", exampleError(f, "example %v is not an integer", value)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64), nil
+}
+
+func exampleError(f Field, format string, args ...interface{}) error {
+	return &FieldError{Field: f.TargetNames[JsonContentType], Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,87 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoExampleCompiles(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.NarrowIntegers = true
+	sg = generate(t, sg, "events.yaml", `
+components:
+  schemas:
+    Event:
+      type: object
+      required: [id, at]
+      example:
+        id: evt-1
+        at: "2024-03-01T10:30:00.5Z"
+        day: "2024-03-01"
+        payload: aGVsbG8=
+        level: 3
+        ratio: 0.5
+        enabled: true
+        status: open
+        tags: [a, b]
+        location: {lat: 1.5}
+      properties:
+        id: {type: string}
+        at: {type: string, format: date-time}
+        day: {type: string, format: date}
+        payload: {type: string, format: byte}
+        level: {type: integer, minimum: 0, maximum: 10}
+        ratio: {type: number, format: float}
+        enabled: {type: boolean}
+        status: {type: string, enum: [open, closed]}
+        tags:
+          type: array
+          items: {type: string}
+        location:
+          type: object
+          nullable: true
+          properties:
+            lat: {type: number, format: double}
+    Day:
+      type: string
+      format: date
+      example: "2024-03-01"
+`)
+	event, err := sg.GoExample("Event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, event, "At: time.Date(2024, 3, 1, 10, 30, 0, 500000000, time.UTC)",
+		"Day: func() *time.Time { v := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); return &v }()",
+		`Payload: []byte("hello")`, "Level: func() *int8 { v := int8(3); return &v }()",
+		`Status: func() *EventStatus { v := EventStatus("open"); return &v }()`, `Tags: []string{"a", "b"}`,
+		"Location: &EventLocation{Lat: func() *float64 { v := float64(1.5); return &v }()}")
+	day, err := sg.GoExample("Day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if day != "Day(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))" {
+		t.Errorf("unexpected example of Day %s", day)
+	}
+	compile(t, map[string]string{
+		"models.go":  render(t, sg),
+		"example.go": "package models\n\nimport \"time\"\n\nvar _ = " + event + "\n\nvar _ = " + day + "\n\nvar _ time.Time\n",
+	})
+}
+
+func TestGoExampleErrors(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "events.yaml", `
+components:
+  schemas:
+    Event:
+      type: object
+      properties:
+        at: {type: string, format: date-time, example: yesterday}
+`)
+	if _, err := sg.GoExample("Event"); err == nil || !strings.Contains(err.Error(), "not a valid date-time") {
+		t.Errorf("expected the invalid date-time to be reported, got %v", err)
+	}
+	if _, err := sg.GoExample("Missing"); err == nil {
+		t.Error("expected an error for a schema that is not registered")
+	}
+}
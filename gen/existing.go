@@ -0,0 +1,125 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// generatedComment matches the comment marking a Go file as generated, as set by the Header option.
+var generatedComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// existingTypes returns the types declared by the hand-written Go files of dir, keyed by name, along with the
+// file declaring each. The files about to be written, the generated files and the tests are left out, as is a
+// missing dir.
+func existingTypes(dir string, written map[string]bool) (map[string]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || written[name] {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if isGenerated(f) {
+			continue
+		}
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+				for _, spec := range gd.Specs {
+					types[spec.(*ast.TypeSpec).Name.Name] = name
+				}
+			}
+		}
+	}
+	return types, nil
+}
+
+// isGenerated reports whether a comment before the package clause marks the file as generated.
+func isGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if generatedComment.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keepExisting leaves out the types declared by the hand-written files of dir, which is reported, when
+// KeepExistingTypes is set and fails otherwise. It returns whether types were left out.
+func (sg SchemaGen) keepExisting(r *renderer, dir string, written map[string]bool) (bool, error) {
+	existing, err := existingTypes(dir, written)
+	if err != nil {
+		return false, err
+	}
+	var types []renderType
+	for _, rt := range r.types {
+		file, ok := existing[rt.Name]
+		if !ok {
+			types = append(types, rt)
+			continue
+		}
+		if !sg.KeepExistingTypes {
+			return false, fmt.Errorf("schema %s: type %s is already declared by %s, rename %s with %s or set KeepExistingTypes",
+				rt.Schema, rt.Name, file, rt.Name, XGoName)
+		}
+		if sg.Diagnostics != nil {
+			sg.Diagnostics.Warn(rt.Name, "type is not generated as %s declares it", file)
+		}
+	}
+	kept := len(types) < len(r.types)
+	r.types = types
+	return kept, nil
+}
+
+// usedImports returns the imports of the file that its source uses, the others having lost the types using them.
+func (sg SchemaGen) usedImports(f renderFile) ([]string, error) {
+	unused := f
+	unused.Imports = nil
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, unused); err != nil {
+		return nil, err
+	}
+	parsed, err := parser.ParseFile(token.NewFileSet(), "", buf.Bytes(), 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the generated source: %w\n%s", err, buf.Bytes())
+	}
+	names := make(map[string]bool)
+	ast.Inspect(parsed, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				names[id.Name] = true
+			}
+		}
+		return true
+	})
+	var imports []string
+	for _, path := range f.Imports {
+		if names[path[strings.LastIndex(path, "/")+1:]] {
+			imports = append(imports, path)
+		}
+	}
+	return imports, nil
+}
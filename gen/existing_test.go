@@ -0,0 +1,103 @@
+package gen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const existingDoc = `
+components:
+  schemas:
+    Event:
+      type: object
+      properties:
+        when:
+          type: object
+          properties:
+            at: {type: string, format: date-time}
+    Stamp: {type: string, format: date-time}
+    Pet:
+      type: object
+      properties:
+        status: {type: string, enum: [available, sold]}
+`
+
+// existingFiles are the files of the target directory, hand-written apart from the test and the generated file.
+var existingFiles = map[string]string{
+	"custom.go": `package models
+
+// EventWhen is written by hand.
+type EventWhen struct {
+	At string ` + "`json:\"at\"`" + `
+}
+
+type (
+	Stamp int64
+)
+`,
+	"custom_test.go": "package models\n\ntype Pet struct{}\n",
+	"old.go":         "// Code generated by turbo-gen. DO NOT EDIT.\n\npackage models\n\ntype Pet struct{}\n",
+}
+
+// existingDir returns a directory holding the existingFiles.
+func existingDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range existingFiles {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExistingTypesClash(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "events.yaml", existingDoc)
+	err := sg.WriteToDir(existingDir(t), "models")
+	if err == nil || !strings.Contains(err.Error(), "type EventWhen is already declared by custom.go") {
+		t.Errorf("expected a clash with custom.go, got %v", err)
+	}
+}
+
+func TestKeepExistingTypes(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.KeepExistingTypes = true
+	sg = generate(t, sg, "events.yaml", existingDoc)
+	dir := existingDir(t)
+	if err := sg.WriteToDir(dir, "models"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	var names []string
+	for _, entry := range entries {
+		b, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(b)
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, " ") != "Event.go Pet.go custom.go custom_test.go old.go" {
+		t.Errorf("unexpected files %v", names)
+	}
+	if strings.Contains(files["Event.go"], "EventWhen struct") || strings.Contains(files["Event.go"], `"time"`) {
+		t.Errorf("expected EventWhen to be left out along with the time import\n%s", files["Event.go"])
+	}
+	assertContains(t, files["Event.go"], "When EventWhen")
+	var warned []string
+	for _, w := range sg.Diagnostics.Warnings {
+		warned = append(warned, w.Field)
+	}
+	if strings.Join(warned, ",") != "EventWhen,Stamp" {
+		t.Errorf("expected warnings for EventWhen and Stamp, got %v", sg.Diagnostics.Warnings)
+	}
+	delete(files, "old.go")
+	delete(files, "custom_test.go")
+	compile(t, files)
+}
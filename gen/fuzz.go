@@ -0,0 +1,132 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// FuzzDir is the directory of the seed corpus written by WriteToDir, relative to the directory of the package.
+const FuzzDir = "testdata/fuzz"
+
+// writeCorpus writes the JSON example of each schema having one as a seed of the fuzz target named after its type,
+// in the corpus file format of go test.
+func (sg SchemaGen) writeCorpus(r *renderer, dir string, names []string) error {
+	for _, name := range names {
+		v, ok := sg.SchemaInfos[name].Fields[name]
+		if !ok {
+			continue
+		}
+		r.schema = name
+		example, err := r.jsonExample(v, nil)
+		if err != nil {
+			return err
+		}
+		if example == nil {
+			continue
+		}
+		b, err := json.Marshal(example)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(FuzzDir), "Fuzz"+fieldOf(v).Name)
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		// Named after the hash of the content as go test does
+		sum := sha256.Sum256(b)
+		seed := "go test fuzz v1\n[]byte(" + strconv.Quote(string(b)) + ")\n"
+		if err := ioutil.WriteFile(filepath.Join(target, hex.EncodeToString(sum[:8])), []byte(seed), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonExample returns the example of the field as a JSON value, nil when there is none. It is built as goValue
+// builds the Go literal of the example, which checks it.
+func (r *renderer) jsonExample(v interface{}, value interface{}) (interface{}, error) {
+	f := fieldOf(v)
+	if _, ok := v.(RefField); ok || !f.IsArray {
+		return r.jsonElem(v, value)
+	}
+	if value == nil && f.Example != nil {
+		value = []interface{}{f.Example}
+	}
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, exampleError(f, "example %v is not an array", value)
+	}
+	elems := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		elem, err := r.jsonElem(v, item)
+		if err != nil {
+			return nil, err
+		}
+		if elem == nil {
+			return nil, exampleError(f, "example %v has an item without a value", value)
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// jsonElem returns a single value of the field as a JSON value, ignoring whether the field is an array. The
+// members of objects are keyed by their JSON names, free-form values are taken as they are.
+func (r *renderer) jsonElem(v interface{}, value interface{}) (interface{}, error) {
+	f := fieldOf(v)
+	if f.Example != nil && !f.IsArray {
+		value = f.Example
+	}
+	switch t := v.(type) {
+	case Field:
+		return value, nil
+	case ObjectField:
+		m, ok := value.(map[string]interface{})
+		if !ok && value != nil {
+			return nil, exampleError(f, "example %v is not an object", value)
+		}
+		o, _ := r.flatten(t)
+		object := make(map[string]interface{})
+		for k, member := range o.Members {
+			elem, err := r.jsonExample(member, m[k])
+			if err != nil {
+				return nil, err
+			}
+			if elem != nil {
+				object[fieldOf(member).TargetNames[JsonContentType]] = elem
+			}
+		}
+		if len(object) == 0 && value == nil {
+			return nil, nil
+		}
+		return object, nil
+	}
+	literal, err := r.goElem(v, value)
+	if literal == "" || err != nil {
+		return nil, err
+	}
+	if value != nil {
+		return value, nil
+	}
+	switch t := v.(type) {
+	case StringField:
+		return *t.Default, nil
+	case NumberField:
+		if t.Default != nil {
+			return *t.Default, nil
+		}
+		return *t.Const, nil
+	case BooleanField:
+		return *t.Default, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+package gen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFuzzCorpus(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.FuzzCorpus = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        pet_name: {type: string, example: Rex}
+        age: {type: integer, default: 3}
+        tags:
+          type: array
+          items: {type: string, example: good}
+        owner: {$ref: '#/components/schemas/Owner'}
+        extra: {example: {any: [1, 2]}}
+    Owner:
+      type: object
+      properties:
+        id: {type: string}
+    Status: {type: string, enum: [available, sold], example: sold}
+`)
+	dir := t.TempDir()
+	if err := sg.WriteToDir(dir, "models"); err != nil {
+		t.Fatal(err)
+	}
+	corpus := make(map[string]string)
+	root := filepath.Join(dir, filepath.FromSlash(FuzzDir))
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		if len(lines) != 2 || lines[0] != "go test fuzz v1" || !strings.HasPrefix(lines[1], "[]byte(") {
+			t.Fatalf("%s is not a corpus file:\n%s", path, b)
+		}
+		doc, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(lines[1], "[]byte("), ")"))
+		if err != nil {
+			return err
+		}
+		if !json.Valid([]byte(doc)) {
+			t.Fatalf("%s holds invalid JSON: %s", path, doc)
+		}
+		target, _ := filepath.Rel(root, filepath.Dir(path))
+		corpus[target] = doc
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"FuzzPet":    `{"age":3,"extra":{"any":[1,2]},"pet_name":"Rex","tags":["good"]}`,
+		"FuzzStatus": `"sold"`,
+	}
+	if len(corpus) != len(expected) {
+		t.Fatalf("expected the corpus of %v, got %v", expected, corpus)
+	}
+	for target, doc := range expected {
+		if corpus[target] != doc {
+			t.Errorf("expected %s for %s, got %s", doc, target, corpus[target])
+		}
+	}
+}
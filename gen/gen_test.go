@@ -0,0 +1,131 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generate adds the component schemas of the YAML document doc, identified by docPath, and generates them.
+func generate(t *testing.T, sg SchemaGen, docPath, doc string) SchemaGen {
+	t.Helper()
+	if err := sg.AddFromReader(strings.NewReader(doc), docPath, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	return sg
+}
+
+// render returns the source rendered for the generated schemas as package models.
+func render(t *testing.T, sg SchemaGen) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := sg.Render(&buf, "models"); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// writeDir writes the files of the generated schemas as package models to a temporary directory and returns their
+// content keyed by file name.
+func writeDir(t *testing.T, sg SchemaGen) map[string]string {
+	t.Helper()
+	return writeDirAt(t, sg, t.TempDir())
+}
+
+// writeDirAt writes the files of the generated schemas as package models to dir and returns their content keyed
+// by file name.
+func writeDirAt(t *testing.T, sg SchemaGen, dir string) map[string]string {
+	t.Helper()
+	if err := sg.WriteToDir(dir, "models"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		b, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(b)
+	}
+	return files
+}
+
+// goCommand runs the go command with args in a module holding the given files and returns its output. The test
+// fails if the command does.
+func goCommand(t *testing.T, files map[string]string, args ...string) string {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not available")
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/models\n\ngo 1.16\n"
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goBin, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		var sources strings.Builder
+		for name, content := range files {
+			sources.WriteString("--- " + name + "\n" + content + "\n")
+		}
+		t.Fatalf("go %s: %v\n%s\n%s", strings.Join(args, " "), err, out, sources.String())
+	}
+	return string(out)
+}
+
+// compile type checks the files as a package.
+func compile(t *testing.T, files map[string]string) {
+	t.Helper()
+	goCommand(t, files, "vet", ".")
+}
+
+// run runs the files as a main package and returns what it prints.
+func run(t *testing.T, files map[string]string) string {
+	t.Helper()
+	return goCommand(t, files, "run", ".")
+}
+
+// assertContains fails the test for each of the fragments missing from s.
+func assertContains(t *testing.T, s string, fragments ...string) {
+	t.Helper()
+	for _, fragment := range fragments {
+		if !strings.Contains(s, fragment) {
+			t.Errorf("missing %q in\n%s", fragment, s)
+		}
+	}
+}
+
+// squeeze collapses the runs of blanks of s, such as the alignment of gofmt, to single spaces.
+func squeeze(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// assertWarning fails the test unless the diagnostics hold a warning for the field whose message contains fragment.
+func assertWarning(t *testing.T, sg SchemaGen, field, fragment string) {
+	t.Helper()
+	for _, w := range sg.Diagnostics.Warnings {
+		if w.Field == field && strings.Contains(w.Message, fragment) {
+			return
+		}
+	}
+	t.Errorf("missing a warning for %s containing %q in %v", field, fragment, sg.Diagnostics.Warnings)
+}
@@ -1,5 +1,5 @@
 package gen
 
 type Generator interface {
-	Generate()
+	Generate() error
 }
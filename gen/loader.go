@@ -0,0 +1,285 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.nandlabs.io/turbo-gen/spec"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ComponentsBasePath is the base path of the schemas declared in the components section of an OAS document.
+const ComponentsBasePath = "#/components/schemas"
+
+// DefaultFetchTimeout bounds the loading of a remote document when SchemaGen.HTTPClient is not set.
+const DefaultFetchTimeout = 30 * time.Second
+
+// MaxDocumentSize bounds the size of a remote document.
+const MaxDocumentSize = 16 << 20
+
+// Format of an OAS document.
+type Format int
+
+const (
+	// FormatAuto detects the format from the content of the document.
+	FormatAuto Format = iota
+	// FormatJSON parses the document as JSON.
+	FormatJSON
+	// FormatYAML parses the document as YAML.
+	FormatYAML
+)
+
+// AddFromReader parses the OAS document read from r using the given format hint and adds all of its
+// component schemas. docPath identifies the document and is used to resolve relative references. A schema
+// clashing with a schema of the same name added from another document is rejected.
+func (sg SchemaGen) AddFromReader(r io.Reader, docPath string, format Format) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("unable to read document %s: %w", docPath, err)
+	}
+	oas, err := parseOAS(b, format)
+	if err != nil {
+		return fmt.Errorf("unable to parse document %s: %w", docPath, err)
+	}
+	return sg.addSchemas(oas, docPath)
+}
+
+// DocumentLoader loads the content of the documents holding the schemas referenced by other documents.
+type DocumentLoader interface {
+	// Load returns the content of the document at doc, an http or https URL or the path of a local file.
+	Load(doc *url.URL) ([]byte, error)
+}
+
+// defaultLoader reads local documents from the file system and fetches remote ones with the client.
+type defaultLoader struct {
+	client *http.Client
+}
+
+func (l defaultLoader) Load(doc *url.URL) ([]byte, error) {
+	if doc.Scheme != "http" && doc.Scheme != "https" {
+		return ioutil.ReadFile(doc.Path)
+	}
+	resp, err := l.client.Get(doc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxDocumentSize {
+		return nil, fmt.Errorf("document exceeds %d bytes", MaxDocumentSize)
+	}
+	return b, nil
+}
+
+// loader returns the Loader, or a loader reading local files and fetching remote documents with the httpClient.
+func (sg SchemaGen) loader() DocumentLoader {
+	if sg.Loader != nil {
+		return sg.Loader
+	}
+	return defaultLoader{client: sg.httpClient()}
+}
+
+// loadDocument loads the document of the resolved reference u and adds its component schemas, unless it is
+// already loaded. The references using http or https must have been checked against the AllowedHosts.
+func (sg SchemaGen) loadDocument(u *url.URL, ctx context.Context) error {
+	doc := documentOf(u)
+	if isLoaded(doc, ctx) {
+		return nil
+	}
+	sg.tracef("loading document %s", doc)
+	docUrl := *u
+	docUrl.Fragment = ""
+	b, err := sg.loader().Load(&docUrl)
+	if err != nil {
+		return fmt.Errorf("unable to read document %s: %w", doc, err)
+	}
+	return sg.addDocument(b, doc, ctx)
+}
+
+// httpClient returns a copy of the HTTPClient, or a client with a DefaultFetchTimeout, that refuses to follow
+// redirects to the hosts that are not allowed.
+func (sg SchemaGen) httpClient() *http.Client {
+	client := &http.Client{Timeout: DefaultFetchTimeout}
+	if sg.HTTPClient != nil {
+		c := *sg.HTTPClient
+		client = &c
+	}
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !sg.isAllowedHost(req.URL) {
+			return fmt.Errorf("redirect to host %s is not allowed", req.URL.Host)
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return client
+}
+
+// isAllowedHost reports whether u is an http or https URL of one of the AllowedHosts.
+func (sg SchemaGen) isAllowedHost(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	for _, host := range sg.AllowedHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// addDocument parses the content of the document at docPath, in the format given by its extension, and adds
+// its component schemas. The parsed document is cached for the rest of the Generate run. A schema clashing with
+// a schema of the same name of another document fails the run.
+func (sg SchemaGen) addDocument(b []byte, docPath string, ctx context.Context) error {
+	oas, err := parseOAS(b, formatOf(docPath))
+	if err != nil {
+		return fmt.Errorf("unable to parse document %s: %w", docPath, err)
+	}
+	if documents, ok := ctx.Value(Documents).(map[string]*spec.OAS); ok {
+		documents[docPath] = oas
+	}
+	return sg.addSchemas(oas, docPath)
+}
+
+// addSchemas records the info of the document and adds its component schemas in name order. The first clash is
+// returned.
+func (sg SchemaGen) addSchemas(oas *spec.OAS, docPath string) error {
+	docUrl, _ := url.Parse(docPath)
+	sg.Infos[docUrl.String()] = oas.Info
+	if oas.Components == nil {
+		return nil
+	}
+	var names []string
+	for k := range oas.Components.Schemas {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var firstErr error
+	for _, k := range names {
+		if err := sg.add(k, docPath, ComponentsBasePath, oas.Components.Schemas[k]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// documentOf returns the document part of the resolved reference u, which identifies the document.
+func documentOf(u *url.URL) string {
+	doc := *u
+	doc.Fragment = ""
+	return doc.String()
+}
+
+// isLoaded reports whether the document at docPath has already been loaded by the Generate run.
+func isLoaded(docPath string, ctx context.Context) bool {
+	documents, _ := ctx.Value(Documents).(map[string]*spec.OAS)
+	_, ok := documents[docPath]
+	return ok
+}
+
+// Column is a member of a flat record added through AddRecord.
+type Column struct {
+	Name string
+	// Type is a scalar schema type (string, integer, number or boolean) or one of the registered formats, such
+	// as int32 or date-time, which stands for the schema type it applies to.
+	Type     string
+	Required bool
+}
+
+// AddRecord adds a schema named name for a flat record holding one member per column, without going through an
+// OAS document. The schema is generated like the component schemas, under ComponentsBasePath of an unnamed
+// document.
+func (sg SchemaGen) AddRecord(name string, columns []Column) error {
+	schema := &spec.Schema{Type: "object", Properties: make(map[string]*spec.Schema)}
+	for _, c := range columns {
+		if _, ok := schema.Properties[c.Name]; ok {
+			return &FieldError{Field: c.Name, Err: fmt.Errorf("duplicate column")}
+		}
+		member, err := columnSchema(c)
+		if err != nil {
+			return &FieldError{Field: c.Name, Err: err}
+		}
+		schema.Properties[c.Name] = member
+		if c.Required {
+			schema.Required = append(schema.Required, c.Name)
+		}
+	}
+	return sg.add(name, "", ComponentsBasePath, schema)
+}
+
+// columnSchema returns the schema of the member holding the values of the column.
+func columnSchema(c Column) (*spec.Schema, error) {
+	switch c.Type {
+	case "string", "integer", "number", "boolean":
+		return &spec.Schema{Type: c.Type}, nil
+	}
+	if types, ok := formatTypes[c.Type]; ok {
+		format := c.Type
+		return &spec.Schema{Type: types[0], Format: &format}, nil
+	}
+	return nil, fmt.Errorf("unsupported column type %s", c.Type)
+}
+
+// detectFormat sniffs the first non-whitespace byte of the content. Objects and arrays are treated as JSON,
+// anything else as YAML.
+func detectFormat(b []byte) Format {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// formatOf returns the format of a document from the extension of its path. Unknown extensions are parsed as JSON.
+func formatOf(docPath string) Format {
+	switch strings.ToLower(path.Ext(docPath)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+func parseOAS(b []byte, format Format) (*spec.OAS, error) {
+	if format == FormatAuto {
+		format = detectFormat(b)
+	}
+	if format == FormatYAML {
+		// Convert YAML to JSON so that both formats share the same decoding rules (numbers, extensions).
+		var v interface{}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		jb, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		b = jb
+	}
+	oas := &spec.OAS{}
+	if err := json.Unmarshal(b, oas); err != nil {
+		return nil, err
+	}
+	return oas, nil
+}
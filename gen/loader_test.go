@@ -0,0 +1,382 @@
+package gen
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// remoteDoc returns a document whose Pet schema references ref.
+func remoteDoc(ref string) string {
+	return `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        owner:
+          $ref: "` + ref + `"
+`
+}
+
+func hostOf(t *testing.T, srv *httptest.Server) string {
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Hostname()
+}
+
+func TestRemoteRefLoadsFromAllowedHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("components:\n  schemas:\n    Owner:\n      type: object\n      properties:\n        name: {type: string}\n"))
+	}))
+	defer srv.Close()
+	sg := NewSchemaGen()
+	sg.AllowedHosts = []string{hostOf(t, srv)}
+	sg.HTTPClient = srv.Client()
+	sg = generate(t, sg, "pets.yaml", remoteDoc(srv.URL+"/owner.yaml#/components/schemas/Owner"))
+	if _, ok := sg.SchemaInfos["Owner"]; !ok {
+		t.Fatal("remote schema Owner not added")
+	}
+	assertContains(t, render(t, sg), "Owner Owner", "type Owner struct")
+}
+
+func TestRemoteRefBlockedHost(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.AllowedHosts = []string{"schemas.example.com"}
+	doc := remoteDoc("https://evil.example.com/owner.yaml#/components/schemas/Owner")
+	if err := sg.AddFromReader(strings.NewReader(doc), "pets.yaml", FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	err := sg.Generate()
+	if err == nil || !strings.Contains(err.Error(), "evil.example.com") {
+		t.Fatalf("expected an error naming the blocked host, got %v", err)
+	}
+}
+
+func TestRemoteRefUnsupportedScheme(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.AllowedHosts = []string{"schemas.example.com"}
+	if err := sg.AddFromReader(strings.NewReader(remoteDoc("ftp://schemas.example.com/owner.yaml")), "pets.yaml", FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.Generate(); err == nil || !strings.Contains(err.Error(), "unsupported protocol ftp") {
+		t.Fatalf("expected an unsupported protocol error, got %v", err)
+	}
+}
+
+// TestRemoteRelativeRefStaysRemote checks that the references of a remote document are resolved against its URL
+// rather than read from the local file system.
+func TestRemoteRelativeRefStaysRemote(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := ioutil.WriteFile(secret, []byte("components:\n  schemas:\n    Secret:\n      type: object\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path == "/pets.yaml" {
+			w.Write([]byte(strings.Replace(remoteDoc(secret+"#/components/schemas/Secret"), "Pet:", "RemotePet:", 1)))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	sg := NewSchemaGen()
+	sg.AllowedHosts = []string{hostOf(t, srv)}
+	sg.HTTPClient = srv.Client()
+	if err := sg.AddFromReader(strings.NewReader(remoteDoc(srv.URL+"/pets.yaml#/components/schemas/RemotePet")), "main.yaml", FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.Generate(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected the remote fetch of the reference to fail, got %v", err)
+	}
+	if _, ok := sg.SchemaInfos["Secret"]; ok {
+		t.Fatal("local document loaded through a remote reference")
+	}
+	if len(requested) != 2 || requested[1] != filepath.ToSlash(secret) {
+		t.Fatalf("expected the reference to be fetched from the remote host, got %v", requested)
+	}
+}
+
+func TestRemoteRedirectToBlockedHost(t *testing.T) {
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect to a blocked host followed")
+	}))
+	defer blocked.Close()
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(blocked.URL, "127.0.0.1", "localhost", 1)+r.URL.Path, http.StatusFound)
+	}))
+	defer allowed.Close()
+	sg := NewSchemaGen()
+	sg.AllowedHosts = []string{hostOf(t, allowed)}
+	if err := sg.AddFromReader(strings.NewReader(remoteDoc(allowed.URL+"/owner.yaml#/components/schemas/Owner")), "pets.yaml", FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.Generate(); err == nil || !strings.Contains(err.Error(), "redirect to host localhost") {
+		t.Fatalf("expected the redirect to be refused, got %v", err)
+	}
+}
+
+func TestExternalRefMissingDocument(t *testing.T) {
+	sg := NewSchemaGen()
+	docPath := filepath.Join(t.TempDir(), "pets.yaml")
+	if err := sg.AddFromReader(strings.NewReader(remoteDoc("missing.yaml#/components/schemas/Owner")), docPath, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	err := sg.Generate()
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "owner" || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a field error wrapping the read failure, got %v", err)
+	}
+}
+
+func TestExternalRefFixture(t *testing.T) {
+	docPath := filepath.Join("testdata", "external", "pets.yaml")
+	b, err := ioutil.ReadFile(docPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := generate(t, NewSchemaGen(), docPath, string(b))
+	for _, name := range []string{"Pet", "Owner", "Address"} {
+		if _, ok := sg.SchemaInfos[name]; !ok {
+			t.Errorf("schema %s not added", name)
+		}
+	}
+	if len(sg.Diagnostics.Warnings) > 0 {
+		t.Errorf("unexpected warnings %v", sg.Diagnostics.Warnings)
+	}
+	assertContains(t, render(t, sg), "Owner Owner", "Address Address", "type Address struct")
+}
+
+func TestExternalRefMalformedDocument(t *testing.T) {
+	sg := NewSchemaGen()
+	docPath := filepath.Join("testdata", "external", "pets.yaml")
+	if err := sg.AddFromReader(strings.NewReader(remoteDoc("broken.yaml#/components/schemas/Owner")), docPath, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	err := sg.Generate()
+	var fe *FieldError
+	if !errors.As(err, &fe) || !strings.Contains(err.Error(), "testdata/external/broken.yaml") {
+		t.Fatalf("expected a field error naming the malformed document, got %v", err)
+	}
+}
+
+// mapLoader serves the documents of a map, keyed by their URL, and counts the loads of each.
+type mapLoader struct {
+	docs  map[string]string
+	loads map[string]int
+}
+
+func (l *mapLoader) Load(doc *url.URL) ([]byte, error) {
+	l.loads[doc.String()]++
+	content, ok := l.docs[doc.String()]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func TestLoaderLoadsEachDocumentOnce(t *testing.T) {
+	loader := &mapLoader{
+		docs: map[string]string{
+			"owner.yaml": "components:\n  schemas:\n    Owner:\n      type: object\n      properties:\n        name: {type: string}\n",
+		},
+		loads: make(map[string]int),
+	}
+	sg := NewSchemaGen()
+	sg.Loader = loader
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        owner:
+          $ref: "owner.yaml#/components/schemas/Owner"
+        previousOwner:
+          $ref: "owner.yaml#/components/schemas/Owner"
+`)
+	if loader.loads["owner.yaml"] != 1 || len(loader.loads) != 1 {
+		t.Errorf("expected owner.yaml to be loaded once, got %v", loader.loads)
+	}
+	assertContains(t, squeeze(render(t, sg)), "Owner Owner", "PreviousOwner Owner", "type Owner struct")
+}
+
+func TestLoaderNotCalledForBlockedHost(t *testing.T) {
+	loader := &mapLoader{loads: make(map[string]int)}
+	sg := NewSchemaGen()
+	sg.Loader = loader
+	sg.AllowedHosts = []string{"schemas.example.com"}
+	if err := sg.AddFromReader(strings.NewReader(remoteDoc("https://evil.example.com/owner.yaml#/components/schemas/Owner")), "pets.yaml", FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.Generate(); err == nil {
+		t.Fatal("expected the blocked host to fail the generation")
+	}
+	if len(loader.loads) > 0 {
+		t.Errorf("loader called for a blocked host: %v", loader.loads)
+	}
+}
+
+func TestAddFromReader(t *testing.T) {
+	const yamlDoc = "components:\n  schemas:\n    Pet:\n      type: object\n"
+	const jsonDoc = `{"components": {"schemas": {"Pet": {"type": "object"}}}}`
+	tests := []struct {
+		name   string
+		doc    string
+		format Format
+	}{
+		{"yaml", yamlDoc, FormatYAML},
+		{"json", jsonDoc, FormatJSON},
+		{"auto yaml", yamlDoc, FormatAuto},
+		{"auto json", "\n  " + jsonDoc, FormatAuto},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sg := NewSchemaGen()
+			if err := sg.AddFromReader(strings.NewReader(tt.doc), "pets", tt.format); err != nil {
+				t.Fatal(err)
+			}
+			si, ok := sg.SchemaInfos["Pet"]
+			if !ok {
+				t.Fatal("schema Pet not added")
+			}
+			if si.Schema.Type != "object" || si.DocPath.String() != "pets" || si.BasePath.String() != ComponentsBasePath {
+				t.Errorf("unexpected schema info %+v", si)
+			}
+		})
+	}
+}
+
+func TestAddFromReaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		format Format
+	}{
+		{"invalid yaml", "components: [unterminated", FormatYAML},
+		{"invalid json", "{", FormatJSON},
+		{"yaml as json", "components:\n  schemas: {}\n", FormatJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewSchemaGen().AddFromReader(strings.NewReader(tt.doc), "pets", tt.format)
+			if err == nil || !strings.Contains(err.Error(), "unable to parse document pets") {
+				t.Errorf("expected a parse error naming the document, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAddFromReaderReadError(t *testing.T) {
+	failure := errors.New("connection reset")
+	err := NewSchemaGen().AddFromReader(iotest.ErrReader(failure), "pets", FormatAuto)
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "unable to read document pets") {
+		t.Errorf("expected the read error naming the document, got %v", err)
+	}
+}
+
+func TestExternalSchemaNameClash(t *testing.T) {
+	sg := NewSchemaGen()
+	docPath := filepath.Join("testdata", "external", "shop.yaml")
+	if err := sg.AddFromReader(strings.NewReader(`
+components:
+  schemas:
+    Owner:
+      type: object
+      properties:
+        email: {type: string}
+    Shop:
+      type: object
+      properties:
+        owner:
+          $ref: "owner.yaml#/components/schemas/Owner"
+`), docPath, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	err := sg.Generate()
+	if err == nil || !strings.Contains(err.Error(), "schema Owner") || !strings.Contains(err.Error(), "owner.yaml") ||
+		!strings.Contains(err.Error(), "shop.yaml") {
+		t.Fatalf("expected the clash of both Owner schemas, got %v", err)
+	}
+	if si := sg.SchemaInfos["Owner"]; si.DocPath.String() != filepath.ToSlash(docPath) {
+		t.Errorf("local Owner replaced by the schema of %s", si.DocPath)
+	}
+}
+
+func TestDocumentsDeclaringTheSameSchema(t *testing.T) {
+	sg := NewSchemaGen()
+	owner := "components:\n  schemas:\n    Owner:\n      type: object\n      properties:\n        %s: {type: string}\n"
+	if err := sg.AddFromReader(strings.NewReader(strings.Replace(owner, "%s", "name", 1)), "pets.yaml", FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	// The same document may be added again
+	if err := sg.AddFromReader(strings.NewReader(strings.Replace(owner, "%s", "name", 1)), "pets.yaml", FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	err := sg.AddFromReader(strings.NewReader(strings.Replace(owner, "%s", "email", 1)), "shops.yaml", FormatYAML)
+	if err == nil || !strings.Contains(err.Error(), `schema Owner of document "shops.yaml"`) {
+		t.Fatalf("expected the second Owner to be rejected, got %v", err)
+	}
+	if err := sg.Generate(); err == nil {
+		t.Fatal("expected Generate to fail on the clash")
+	}
+	if _, ok := sg.SchemaInfos["Owner"].Schema.Properties["name"]; !ok {
+		t.Error("Owner of pets.yaml replaced")
+	}
+	if err := sg.AddRecord("Owner", []Column{{Name: "id", Type: "string"}}); err == nil {
+		t.Error("expected the record to clash with Owner")
+	}
+}
+
+func TestAddRecord(t *testing.T) {
+	sg := NewSchemaGen()
+	err := sg.AddRecord("user_row", []Column{
+		{Name: "id", Type: "int32", Required: true},
+		{Name: "email", Type: "string", Required: true},
+		{Name: "score", Type: "number"},
+		{Name: "active", Type: "boolean"},
+		{Name: "joined", Type: "date-time"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	files := writeDir(t, sg)
+	if len(files) != 1 {
+		t.Fatalf("expected UserRow.go, got %v", files)
+	}
+	assertContains(t, squeeze(files["UserRow.go"]), "package models", "type UserRow struct {",
+		"ID int32 `json:\"id\"`", "Email string `json:\"email\"`", "Score *float64 `json:\"score,omitempty\"`",
+		"Active *bool `json:\"active,omitempty\"`", "Joined *time.Time `json:\"joined,omitempty\"`")
+	compile(t, files)
+}
+
+func TestAddRecordErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []Column
+	}{
+		{"duplicate", []Column{{Name: "id", Type: "string"}, {Name: "id", Type: "integer"}}},
+		{"unsupported", []Column{{Name: "id", Type: "uuid4"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fe *FieldError
+			if err := NewSchemaGen().AddRecord("Row", tt.columns); !errors.As(err, &fe) || fe.Field != "id" {
+				t.Errorf("expected a field error for id, got %v", err)
+			}
+		})
+	}
+}
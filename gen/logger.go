@@ -0,0 +1,13 @@
+package gen
+
+// Logger receives the trace messages emitted while resolving references, merging compositions and generating fields.
+type Logger interface {
+	Tracef(format string, args ...interface{})
+}
+
+// tracef forwards the message to the configured Logger if any.
+func (sg SchemaGen) tracef(format string, args ...interface{}) {
+	if sg.Logger != nil {
+		sg.Logger.Tracef(format, args...)
+	}
+}
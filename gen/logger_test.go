@@ -0,0 +1,40 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recordingLogger records the trace messages it receives.
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Tracef(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestLoggerReceivesTraces(t *testing.T) {
+	logger := &recordingLogger{}
+	sg := NewSchemaGen()
+	sg.Logger = logger
+	generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Named'
+        - type: object
+          properties:
+            status: {type: string, enum: [available, sold]}
+    Named:
+      type: object
+      properties:
+        name: {type: string}
+`)
+	traces := strings.Join(logger.messages, "\n")
+	assertContains(t, traces, "adding schema #/components/schemas/Pet from pets.yaml", "generating schema Pet",
+		"merging 2 allOf schemas into Pet", "resolving reference #/components/schemas/Named",
+		"generating enum PetStatus with 2 values")
+}
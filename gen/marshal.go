@@ -0,0 +1,378 @@
+package gen
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONFile is the name of the file WriteToDir writes the functions shared by the AppendJSON methods to.
+const JSONFile = "json.go"
+
+// jsonImports are the packages used by the jsonHelpers.
+var jsonImports = []string{"encoding/base64", "encoding/json", "math", "strconv", "unicode/utf8"}
+
+// jsonHelpers are the functions shared by the AppendJSON methods. Strings are escaped and floats formatted as
+// encoding/json does.
+const jsonHelpers = `
+const jsonHex = "0123456789abcdef"
+
+// appendJSONString appends the JSON string of s to b.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', jsonHex[c>>4], jsonHex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			start = i + size
+		} else if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', jsonHex[r&0xF])
+			start = i + size
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// appendJSONFloat appends the JSON number of f, of the given bit size, to b.
+func appendJSONFloat(b []byte, f float64, bits int) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, &json.UnsupportedValueError{Str: strconv.FormatFloat(f, 'g', -1, bits)}
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// Exponents such as e-09 are written e-9
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+// appendJSONBytes appends v to b as a base64 JSON string, null if v is nil.
+func appendJSONBytes(b, v []byte) []byte {
+	if v == nil {
+		return append(b, "null"...)
+	}
+	n := base64.StdEncoding.EncodedLen(len(v))
+	b = append(b, '"')
+	if cap(b)-len(b) < n+1 {
+		grown := make([]byte, len(b), 2*cap(b)+n+1)
+		copy(grown, b)
+		b = grown
+	}
+	base64.StdEncoding.Encode(b[len(b):len(b)+n], v)
+	return append(b[:len(b)+n], '"')
+}
+
+// appendJSONValue appends the JSON encoding of v by encoding/json to b.
+func appendJSONValue(b []byte, v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, raw...), nil
+}
+`
+
+// declareAppendJSON declares the AppendJSON and MarshalJSON methods of the structs, slices and maps. The types
+// declaring JSON methods of their own, including those marked for easyjson, are left out, which is reported for
+// the structs.
+func (r *renderer) declareAppendJSON() {
+	a := appender{r: r}
+	for i := range r.types {
+		rt := &r.types[i]
+		if rt.Underlying == "struct{}" && ownJSON(*rt) && r.sg.Diagnostics != nil {
+			r.sg.Diagnostics.Warn(rt.Name, "struct gets no AppendJSON method as it declares JSON methods")
+		}
+		if !a.hasAppend(rt.Name) {
+			continue
+		}
+		r.schema = rt.Schema
+		a.fallible = false
+		var body []string
+		if rt.Underlying == "struct{}" {
+			body = append(body, "b = append(b, '{')")
+			fields, _ := a.fields("v", *rt, make(map[string]bool), map[string]bool{rt.Name: true})
+			body = append(body, fields...)
+			body = append(body, "if b[len(b)-1] == ',' {", "b[len(b)-1] = '}'", "} else {", "b = append(b, '}')", "}")
+		} else {
+			body = a.value("v", parseType(rt.Underlying), 1)
+		}
+		if a.fallible {
+			body = append([]string{"var err error"}, body...)
+		}
+		rt.Methods = append(rt.Methods,
+			renderMethod{
+				Doc: []string{"AppendJSON appends the JSON encoding of the " + rt.Name + " to b, without reflection, and returns the",
+					"extended buffer. Reusing the buffer saves the allocations of MarshalJSON."},
+				Signature: "(v " + rt.Name + ") AppendJSON(b []byte) ([]byte, error)",
+				Body:      append(body, "return b, nil"),
+			},
+			renderMethod{
+				Doc:       []string{"MarshalJSON encodes the " + rt.Name + " with AppendJSON."},
+				Signature: "(v " + rt.Name + ") MarshalJSON() ([]byte, error)",
+				Body:      []string{"return v.AppendJSON(nil)"},
+			})
+	}
+}
+
+// ownJSON reports whether the type declares JSON methods of its own.
+func ownJSON(rt renderType) bool {
+	return rt.Extra || rt.Additional != "" || rt.NilAsEmpty || rt.EasyJSON || rt.Presence != nil
+}
+
+// appender renders the statements of the AppendJSON methods, appending to b.
+type appender struct {
+	r        *renderer
+	fallible bool // Whether the statements rendered assign err
+}
+
+// hasAppend reports whether the declared type gets an AppendJSON method.
+func (a *appender) hasAppend(name string) bool {
+	rt := a.r.typeNamed(name)
+	return rt != nil && !ownJSON(*rt) && (rt.Underlying == "struct{}" || parseType(rt.Underlying).Elem != nil)
+}
+
+// check returns the statements assigning b and err with the call and returning the error.
+func (a *appender) check(call string) []string {
+	a.fallible = true
+	return []string{"if b, err = " + call + "; err != nil {", "return nil, err", "}"}
+}
+
+// jsonKey returns the JSON key of the struct field and whether its tag has omitempty. The key is empty for the
+// fields left out of the encoding.
+func jsonKey(f renderField) (string, bool) {
+	tag := reflect.StructTag(strings.Trim(f.Tag, "`")).Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	key := parts[0]
+	if key == "" {
+		key = selector(f)
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			return key, true
+		}
+	}
+	return key, false
+}
+
+// base returns the struct embedded by the field, nil if the field is not an embedded struct.
+func (a *appender) base(f renderField) *renderType {
+	if !f.Embedded {
+		return nil
+	}
+	if rt := a.r.typeNamed(strings.TrimPrefix(f.Type, "*")); rt != nil && rt.Underlying == "struct{}" {
+		return rt
+	}
+	return nil
+}
+
+// fields returns the statements appending the fields of the struct held by recv, each followed by a comma, in
+// declaration order, along with their keys. The fields of the embedded structs are promoted in their place, apart
+// from those whose key is hidden by a shallower field or taken by a field promoted before, as encoding/json does.
+func (a *appender) fields(recv string, rt renderType, hidden, onPath map[string]bool) ([]string, map[string]bool) {
+	own := make(map[string]bool)
+	for _, f := range rt.Fields {
+		if key, _ := jsonKey(f); key != "" && a.base(f) == nil {
+			own[key] = true
+		}
+	}
+	var lines []string
+	keys := make(map[string]bool)
+	for _, f := range rt.Fields {
+		expr := recv + "." + selector(f)
+		if base := a.base(f); base != nil {
+			if onPath[base.Name] {
+				continue
+			}
+			taken := make(map[string]bool)
+			for _, set := range []map[string]bool{hidden, own, keys} {
+				for k := range set {
+					taken[k] = true
+				}
+			}
+			onPath[base.Name] = true
+			promoted, promotedKeys := a.fields(expr, *base, taken, onPath)
+			onPath[base.Name] = false
+			if strings.HasPrefix(f.Type, "*") && len(promoted) > 0 {
+				promoted = append(append([]string{"if " + expr + " != nil {"}, promoted...), "}")
+			}
+			lines = append(lines, promoted...)
+			for k := range promotedKeys {
+				keys[k] = true
+			}
+			continue
+		}
+		key, omitEmpty := jsonKey(f)
+		if key == "" || hidden[key] {
+			continue
+		}
+		keys[key] = true
+		raw, _ := json.Marshal(key)
+		t := parseType(f.Type)
+		cond := a.nonEmpty(expr, t)
+		value := a.value(expr, t, 1)
+		if omitEmpty && t.Elem != nil {
+			// Left out when nil
+			value = a.nonNil(expr, t, 1)
+		}
+		field := append([]string{"b = append(b, " + strconv.Quote(string(raw)+":") + "...)"}, value...)
+		field = append(field, "b = append(b, ',')")
+		if omitEmpty && cond != "" {
+			field = append(append([]string{"if " + cond + " {"}, field...), "}")
+		}
+		lines = append(lines, field...)
+	}
+	return lines, keys
+}
+
+// value returns the statements appending the JSON encoding of expr, of type t, null for the nil pointers, slices
+// and maps. depth numbers the variables of the nested loops.
+func (a *appender) value(expr string, t *goType, depth int) []string {
+	if t.Elem != nil && !isBytes(t) {
+		return append(append([]string{"if " + expr + " == nil {", `b = append(b, "null"...)`, "} else {"},
+			a.nonNil(expr, t, depth)...), "}")
+	}
+	return a.nonNil(expr, t, depth)
+}
+
+// isBytes reports whether the type is a byte slice.
+func isBytes(t *goType) bool {
+	return t.Prefix == "[]" && t.Elem.Elem == nil && (t.Elem.Name == "byte" || t.Elem.Name == "uint8")
+}
+
+// nonNil returns the statements appending the JSON encoding of expr, of type t, known not to be a nil pointer,
+// slice or map. The declared types declaring JSON methods of their own and the free-form values are encoded by
+// encoding/json, raw JSON is appended as it is.
+func (a *appender) nonNil(expr string, t *goType, depth int) []string {
+	n := strconv.Itoa(depth)
+	// Operand of the selectors and index expressions
+	operand := expr
+	if strings.HasPrefix(expr, "*") {
+		operand = "(" + expr + ")"
+	}
+	switch {
+	case isBytes(t):
+		return []string{"b = appendJSONBytes(b, " + expr + ")"}
+	case t.Prefix == "*":
+		return a.value("*"+operand, t.Elem, depth+1)
+	case t.Prefix == "[]":
+		lines := []string{"b = append(b, '[')", "for i" + n + ", e" + n + " := range " + expr + " {",
+			"if i" + n + " > 0 {", "b = append(b, ',')", "}"}
+		lines = append(lines, a.value("e"+n, t.Elem, depth+1)...)
+		return append(lines, "}", "b = append(b, ']')")
+	case t.Prefix == "map[string]":
+		a.r.addImport("sort")
+		keys := "keys" + n
+		lines := []string{keys + " := make([]string, 0, len(" + expr + "))", "for k" + n + " := range " + expr + " {",
+			keys + " = append(" + keys + ", k" + n + ")", "}", "sort.Strings(" + keys + ")", "b = append(b, '{')",
+			"for i" + n + ", k" + n + " := range " + keys + " {", "if i" + n + " > 0 {", "b = append(b, ',')", "}",
+			"b = appendJSONString(b, k" + n + ")", "b = append(b, ':')"}
+		lines = append(lines, a.value(operand+"[k"+n+"]", t.Elem, depth+1)...)
+		return append(lines, "}", "b = append(b, '}')")
+	}
+	if a.hasAppend(t.Name) {
+		return a.check(operand + ".AppendJSON(b)")
+	}
+	if rt := a.r.typeNamed(t.Name); rt != nil {
+		// The named types get no JSON methods from their underlying type
+		if u := parseType(rt.Underlying); !ownJSON(*rt) && u.Elem == nil && (comparableTypes[u.Name] || a.hasAppend(u.Name)) {
+			return a.value(u.Name+"("+expr+")", u, depth)
+		}
+		return a.check("appendJSONValue(b, " + expr + ")")
+	}
+	switch t.Name {
+	case "string":
+		return []string{"b = appendJSONString(b, " + expr + ")"}
+	case "bool":
+		a.r.addImport("strconv")
+		return []string{"b = strconv.AppendBool(b, " + expr + ")"}
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		a.r.addImport("strconv")
+		return []string{"b = strconv.AppendInt(b, int64(" + expr + "), 10)"}
+	case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+		a.r.addImport("strconv")
+		return []string{"b = strconv.AppendUint(b, uint64(" + expr + "), 10)"}
+	case "float32":
+		return a.check("appendJSONFloat(b, float64(" + expr + "), 32)")
+	case "float64":
+		return a.check("appendJSONFloat(b, " + expr + ", 64)")
+	case "time.Time":
+		return []string{"b = append(b, '\"')", "b = " + operand + ".AppendFormat(b, time.RFC3339Nano)", "b = append(b, '\"')"}
+	case "json.RawMessage":
+		return []string{"if " + expr + " == nil {", `b = append(b, "null"...)`, "} else {", "b = append(b, " + expr + "...)", "}"}
+	}
+	return a.check("appendJSONValue(b, " + expr + ")")
+}
+
+// nonEmpty returns the condition under which expr, of type t, is not left out by omitempty, empty if it never is.
+func (a *appender) nonEmpty(expr string, t *goType) string {
+	switch t.Prefix {
+	case "*":
+		return expr + " != nil"
+	case "[]", "map[string]":
+		return "len(" + expr + ") != 0"
+	}
+	if rt := a.r.typeNamed(t.Name); rt != nil {
+		if rt.Underlying == "struct{}" {
+			return ""
+		}
+		return a.nonEmpty(expr, parseType(rt.Underlying))
+	}
+	switch t.Name {
+	case "string", "json.RawMessage":
+		return "len(" + expr + ") != 0"
+	case "bool":
+		return expr
+	case "interface{}":
+		return expr + " != nil"
+	case "time.Time":
+		return ""
+	}
+	if comparableTypes[t.Name] {
+		return expr + " != 0"
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+const appendJSONDoc = `
+components:
+  schemas:
+    Named:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        id: {type: integer}
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Named'
+        - type: object
+          required: [weight, status]
+          properties:
+            id: {type: string}
+            status: {type: string, enum: [available, sold]}
+            weight: {type: number}
+            ratio: {type: number, format: float}
+            good: {type: boolean}
+            tags:
+              type: array
+              items: {type: string}
+            best:
+              $ref: '#/components/schemas/Pet'
+            friends:
+              type: array
+              items: {$ref: '#/components/schemas/Pet'}
+            scores:
+              type: object
+              additionalProperties: {type: integer}
+            born: {type: string, format: date-time}
+            photo: {type: string, format: byte}
+            extra: {}
+    Pets:
+      type: array
+      items: {$ref: '#/components/schemas/Pet'}
+`
+
+func TestAppendJSON(t *testing.T) {
+	main := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+func main() {
+	id, good, ratio := "p<1>", false, float32(0.1)
+	born := time.Date(2020, 1, 2, 3, 4, 5, 6, time.FixedZone("X", 3600))
+	pets := Pets{
+		{Named: Named{Name: "fido \"the\" dog\n\t\x01 &   café"}, Weight: 1e21, Status: PetStatusSold},
+		{
+			Named:   Named{Name: "rex"},
+			ID:      &id,
+			Weight:  0.000001,
+			Ratio:   &ratio,
+			Good:    &good,
+			Tags:    []string{"a", ""},
+			Best:    &Pet{Named: Named{Name: "max"}, Weight: -1.5e-7},
+			Friends: []Pet{{Named: Named{Name: "bo"}}},
+			Scores:  map[string]int64{"z": 1, "a": -2},
+			Born:    &born,
+			Photo:   []byte("hello"),
+			Extra:   map[string]interface{}{"k": []interface{}{1.0, "v"}},
+		},
+	}
+	for _, v := range []interface{}{pets, Pets{}, Pets(nil), Pet{Tags: []string{}}} {
+		b, err := json.Marshal(v)
+		fmt.Println(string(b), err)
+	}
+	_, err := json.Marshal(Pet{Weight: math.Inf(1)})
+	fmt.Println(err != nil)
+}
+`
+	outputs := make([]string, 2)
+	for i, appendJSON := range []bool{false, true} {
+		sg := NewSchemaGen()
+		sg.AppendJSON = appendJSON
+		sg = generate(t, sg, "pets.yaml", appendJSONDoc)
+		source := render(t, sg)
+		if appendJSON {
+			assertContains(t, source, "func (v Pet) AppendJSON(b []byte) ([]byte, error) {",
+				"func (v Pets) MarshalJSON() ([]byte, error) {", "func appendJSONString(b []byte, s string) []byte {")
+		}
+		outputs[i] = run(t, map[string]string{
+			"models.go": strings.Replace(source, "package models", "package main", 1),
+			"main.go":   main,
+		})
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("expected the encoding of encoding/json\n%s\ngot\n%s", outputs[0], outputs[1])
+	}
+}
+
+func TestAppendJSONAllocations(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.AppendJSON = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name, weight]
+      properties:
+        name: {type: string}
+        status: {type: string, enum: [available, sold]}
+        age: {type: integer}
+        weight: {type: number}
+        tags:
+          type: array
+          items: {type: string}
+        scores:
+          type: object
+          additionalProperties: {type: number}
+        born: {type: string, format: date-time}
+`)
+	files := writeDir(t, sg)
+	if _, ok := files[JSONFile]; !ok {
+		t.Fatalf("expected %s, got %v", JSONFile, files)
+	}
+	files["models_test.go"] = `package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// plainPet is encoded by reflection as it has no methods.
+type plainPet Pet
+
+func pet() Pet {
+	age, status := int64(3), PetStatusSold
+	born := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	return Pet{Name: "fido", Status: &status, Age: &age, Weight: 12.5, Tags: []string{"good", "small"},
+		Scores: map[string]float64{"speed": 0.5}, Born: &born}
+}
+
+func TestAllocations(t *testing.T) {
+	v := pet()
+	buf := make([]byte, 0, 1024)
+	appendAllocs := testing.AllocsPerRun(100, func() {
+		if _, err := v.AppendJSON(buf[:0]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	jsonAllocs := testing.AllocsPerRun(100, func() {
+		if _, err := json.Marshal(plainPet(v)); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Logf("AppendJSON: %v allocations, encoding/json: %v allocations", appendAllocs, jsonAllocs)
+	if appendAllocs >= jsonAllocs {
+		t.Errorf("expected fewer allocations than encoding/json")
+	}
+}
+
+func BenchmarkAppendJSON(b *testing.B) {
+	v := pet()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = v.AppendJSON(buf[:0])
+	}
+}
+
+func BenchmarkEncodingJSON(b *testing.B) {
+	v := plainPet(pet())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		json.Marshal(v)
+	}
+}
+`
+	out := goCommand(t, files, "test", "-v", "-run", "TestAllocations", "-bench", ".", "-benchtime", "1000x")
+	assertContains(t, out, "BenchmarkAppendJSON", "BenchmarkEncodingJSON")
+	t.Log(out)
+}
@@ -0,0 +1,147 @@
+package gen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NameStrategy derives the Go identifiers of the generated types and fields from the schema and property names.
+// The names it returns are stripped of the runes that are not valid in an identifier.
+type NameStrategy interface {
+	// FieldName returns the name of the struct field or type generated for name.
+	FieldName(name string) string
+	// VarName returns the name of a variable holding a value of name.
+	VarName(name string) string
+}
+
+// DefaultNames is the NameStrategy used when SchemaGen.Names is not set.
+var DefaultNames NameStrategy = PascalCase{}
+
+// PascalCase converts names to PascalCase. Underscores, hyphens and the other runes that are neither letters
+// nor digits separate the words of the name, as do the case changes of camelCase and PascalCase names. The
+// first letter of each word is upper cased and the rest is kept as is, words that are initialisms, such as id
+// or url, are upper cased entirely. Converting a name that is already in PascalCase returns it unchanged.
+type PascalCase struct {
+	// Initialisms extends the built-in list of initialisms.
+	Initialisms []string
+}
+
+// commonInitialisms lists the words PascalCase upper cases entirely, after the Go naming conventions.
+var commonInitialisms = map[string]bool{
+	"ACL":   true,
+	"API":   true,
+	"ASCII": true,
+	"CPU":   true,
+	"CSS":   true,
+	"DNS":   true,
+	"EOF":   true,
+	"GUID":  true,
+	"HTML":  true,
+	"HTTP":  true,
+	"HTTPS": true,
+	"ID":    true,
+	"IP":    true,
+	"JSON":  true,
+	"JWT":   true,
+	"QPS":   true,
+	"RAM":   true,
+	"RPC":   true,
+	"SLA":   true,
+	"SMTP":  true,
+	"SQL":   true,
+	"SSH":   true,
+	"TCP":   true,
+	"TLS":   true,
+	"TTL":   true,
+	"UDP":   true,
+	"UI":    true,
+	"UID":   true,
+	"URI":   true,
+	"URL":   true,
+	"UTF8":  true,
+	"UUID":  true,
+	"VM":    true,
+	"XML":   true,
+	"XMPP":  true,
+	"XSRF":  true,
+	"XSS":   true,
+}
+
+func (p PascalCase) FieldName(name string) string {
+	var sb strings.Builder
+	for _, word := range splitWords(name) {
+		if upper := strings.ToUpper(word); p.isInitialism(upper) {
+			sb.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	return sb.String()
+}
+
+func (p PascalCase) VarName(name string) string {
+	return p.FieldName(name)
+}
+
+// isInitialism reports whether the upper cased word is a built-in or a configured initialism.
+func (p PascalCase) isInitialism(upper string) bool {
+	if commonInitialisms[upper] {
+		return true
+	}
+	for _, i := range p.Initialisms {
+		if strings.ToUpper(i) == upper {
+			return true
+		}
+	}
+	return false
+}
+
+// splitWords splits name at the runes that are neither letters nor digits and at its case changes: before an
+// upper case letter following a lower case letter or a digit, as in userId, and before the last upper case
+// letter of a run followed by a lower case letter, as in HTTPServer.
+func splitWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := word[len(word)-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next) {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// names returns the NameStrategy of the generator.
+func (sg SchemaGen) names() NameStrategy {
+	if sg.Names != nil {
+		return sg.Names
+	}
+	return DefaultNames
+}
+
+// getFieldName returns the identifier ns gives to the type or field generated for name.
+func getFieldName(ns NameStrategy, name string) string {
+	return toIdentifier(ns.FieldName(name))
+}
+
+func getVarName(ns NameStrategy, name string) string {
+	return toIdentifier(ns.VarName(name))
+}
@@ -0,0 +1,72 @@
+package gen
+
+import "testing"
+
+func TestPascalCaseFieldName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"id", "ID"},
+		{"ID", "ID"},
+		{"url", "URL"},
+		{"userId", "UserID"},
+		{"ownerID", "OwnerID"},
+		{"Id", "ID"},
+		{"user_id", "UserID"},
+		{"api_url", "APIURL"},
+		{"HTTPServer", "HTTPServer"},
+		{"httpServer", "HTTPServer"},
+		{"UserName", "UserName"},
+		{"user-name", "UserName"},
+		{"x.y z", "XYZ"},
+		{"ipv4", "Ipv4"},
+		{"page2Size", "Page2Size"},
+		{"identity", "Identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getFieldName(PascalCase{}, tt.name); got != tt.want {
+				t.Errorf("FieldName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPascalCaseCustomInitialisms(t *testing.T) {
+	ns := PascalCase{Initialisms: []string{"sku", "ipv4"}}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"sku", "SKU"},
+		{"item_sku", "ItemSKU"},
+		{"ipv4Address", "IPV4Address"},
+		{"id", "ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getFieldName(ns, tt.name); got != tt.want {
+				t.Errorf("FieldName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldNameIsIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"1st", "X1st"},
+		{"", "X"},
+		{"$ref", "Ref"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getFieldName(DefaultNames, tt.name); got != tt.want {
+				t.Errorf("FieldName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
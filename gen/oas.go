@@ -0,0 +1,176 @@
+package gen
+
+import (
+	"fmt"
+	"go.nandlabs.io/turbo-gen/spec"
+	"strings"
+)
+
+// ToOAS reconstructs the component schemas of an OAS document from the generated field models. Types,
+// required members, constraints, defaults, examples, documentation, XML names and the x-go extensions are restored.
+// Formats are only restored when they determine the Go type. All schemas are expected to have been generated.
+func (sg SchemaGen) ToOAS() (spec.OAS, error) {
+	oas := spec.OAS{
+		OpenAPI:    "3.1.0",
+		Components: &spec.Components{Schemas: make(map[string]*spec.Schema)},
+	}
+	for name, si := range sg.SchemaInfos {
+		v, ok := si.Fields[name]
+		if !ok {
+			return oas, fmt.Errorf("schema %s has not been generated", name)
+		}
+		oas.Components.Schemas[name] = toSchema(sg.names(), name, v)
+	}
+	return oas, nil
+}
+
+// toSchema returns the schema of the field stored under name.
+func toSchema(ns NameStrategy, name string, v interface{}) *spec.Schema {
+	f := fieldOf(v)
+	schema := &spec.Schema{Title: f.Title, Description: f.Description, Comment: f.Comment, Example: f.Example, Nullable: f.Nullable}
+	switch t := v.(type) {
+	case RefField:
+		ref := t.Reference
+		schema.Ref = &ref
+		schema.Default = t.Default
+		if t.Embedded {
+			setExtension(schema, XGoEmbedded, true)
+		}
+	case StringField:
+		schema.Type = "string"
+		schema.Pattern = t.Pattern
+		schema.MinLength = t.MinLen
+		schema.MaxLength = t.MaxLen
+		schema.Format = t.Format
+		if t.Default != nil {
+			schema.Default = *t.Default
+		}
+	case NumberField:
+		schema.Type = numericType(t.Type)
+		schema.Format = numericFormat(t.Type)
+		schema.Minimum = t.Min
+		schema.Maximum = t.Max
+		schema.ExclusiveMinimum = t.MinExclusive
+		schema.ExclusiveMaximum = t.MaxExclusive
+		schema.MultipleOf = t.MultipleOf
+		if t.Default != nil {
+			schema.Default = *t.Default
+		}
+	case BooleanField:
+		schema.Type = "boolean"
+		if t.Default != nil {
+			schema.Default = *t.Default
+		}
+	case EnumField:
+		schema.Type = "string"
+		if t.BaseType != "string" {
+			schema.Type = numericType(t.BaseType)
+			schema.Format = numericFormat(t.BaseType)
+		}
+		schema.Enum = t.Values
+	case ObjectField:
+		schema.Type = "object"
+		schema.Required = t.RequiredFields
+		if t.MinProperties > 0 {
+			minProperties := t.MinProperties
+			schema.MinProperties = &minProperties
+		}
+		if t.MaxProperties > 0 {
+			maxProperties := t.MaxProperties
+			schema.MaxProperties = &maxProperties
+		}
+		if len(t.Members) > 0 {
+			schema.Properties = make(map[string]*spec.Schema)
+			for k, member := range t.Members {
+				schema.Properties[k] = toSchema(ns, k, member)
+			}
+		}
+		for _, value := range t.AdditionalProperties {
+			if vf, ok := value.(Field); ok && vf.Type == "interface{}" {
+				schema.AdditionalProperties = true
+			} else {
+				schema.AdditionalProperties = toSchema(ns, name+"Value", value)
+			}
+		}
+	case Field:
+		if t.Type == "json.RawMessage" {
+			setExtension(schema, XGoRaw, true)
+		}
+	}
+
+	if f.Name != getFieldName(ns, name) {
+		setExtension(schema, XGoName, f.Name)
+	}
+	if f.OmitEmpty != nil {
+		setExtension(schema, XGoOmitEmpty, *f.OmitEmpty)
+	}
+	if len(f.Implements) > 0 {
+		setExtension(schema, XGoImplements, f.Implements)
+	}
+	if f.Xml != nil {
+		schema.Xml = toXml(name, f)
+	}
+	if f.IsArray {
+		array := &spec.Schema{Type: "array", Items: schema}
+		if xmlName := f.TargetNames[XmlContentType]; strings.Contains(xmlName, ">") {
+			wrapper := xmlName[:strings.Index(xmlName, ">")]
+			wrapped := true
+			array.Xml = &spec.Xml{Name: &wrapper, Wrapped: &wrapped}
+		}
+		return array
+	}
+	return schema
+}
+
+// numericType returns the schema type of a numeric Go type.
+func numericType(goType string) string {
+	if strings.HasPrefix(goType, "float") {
+		return "number"
+	}
+	return "integer"
+}
+
+// numericFormat returns the format selecting a numeric Go type that is not the default of its schema type, nil
+// for the other types.
+func numericFormat(goType string) *string {
+	var format string
+	switch goType {
+	case "int32":
+		format = "int32"
+	case "float32":
+		format = "float"
+	default:
+		return nil
+	}
+	return &format
+}
+
+func toXml(name string, f Field) *spec.Xml {
+	xml := &spec.Xml{}
+	xmlName := f.TargetNames[XmlContentType]
+	xmlName = xmlName[strings.LastIndex(xmlName, ">")+1:]
+	xmlName = xmlName[strings.Index(xmlName, ":")+1:]
+	if xmlName != name {
+		xml.Name = &xmlName
+	}
+	if f.Xml.Prefix != "" {
+		prefix := f.Xml.Prefix
+		xml.Prefix = &prefix
+	}
+	if f.Xml.Namespace != "" {
+		namespace := f.Xml.Namespace
+		xml.Namespace = &namespace
+	}
+	if f.Xml.Attribute {
+		attribute := true
+		xml.Attribute = &attribute
+	}
+	return xml
+}
+
+func setExtension(schema *spec.Schema, key string, value interface{}) {
+	if schema.SpecExtension == nil {
+		schema.SpecExtension = make(spec.SpecExtension)
+	}
+	schema.SpecExtension[key] = value
+}
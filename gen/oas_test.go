@@ -0,0 +1,130 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToOASRoundTrip(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          x-go-name: Label
+        weight: {type: number, format: float}
+        score: {type: number, format: double}
+        age: {type: integer, format: int32}
+        born: {type: string, format: date}
+        status: {type: string, enum: [sold, available]}
+        tags:
+          type: array
+          items: {type: string}
+        owner:
+          $ref: "#/components/schemas/Owner"
+        extra:
+          type: object
+          additionalProperties: {type: integer}
+    Owner:
+      type: object
+      properties:
+        name: {type: string}
+`)
+	oas, err := sg.ToOAS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(oas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, empty := range []string{`"discriminator"`, `"externalDocs"`} {
+		if strings.Contains(string(b), empty) {
+			t.Errorf("empty %s encoded in %s", empty, b)
+		}
+	}
+	again := NewSchemaGen()
+	if err := again.AddFromReader(bytes.NewReader(b), "pets.json", FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := again.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if before, after := render(t, sg), render(t, again); before != after {
+		t.Errorf("round trip changed the source\nbefore:\n%s\nafter:\n%s\nthrough:\n%s", before, after, b)
+	}
+}
+
+func TestPropertyCountBounds(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "labels.yaml", `
+components:
+  schemas:
+    Labels:
+      type: object
+      minProperties: 1
+      maxProperties: 5
+      additionalProperties: {type: string}
+`)
+	labels := sg.SchemaInfos["Labels"].Fields["Labels"].(ObjectField)
+	if labels.MinProperties != 1 || labels.MaxProperties != 5 {
+		t.Errorf("expected the bounds 1 and 5, got %d and %d", labels.MinProperties, labels.MaxProperties)
+	}
+	oas, err := sg.ToOAS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := oas.Components.Schemas["Labels"]
+	if schema.MinProperties == nil || *schema.MinProperties != 1 || schema.MaxProperties == nil || *schema.MaxProperties != 5 {
+		t.Errorf("expected ToOAS to restore the bounds, got %v and %v", schema.MinProperties, schema.MaxProperties)
+	}
+}
+
+func TestToOASDocumentation(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.EmitComments = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      title: is an animal kept at home.
+      description: Pets are listed by the store.
+      $comment: Kept in sync with the store.
+      properties:
+        name: {type: string, description: The name the pet answers to., $comment: Not unique.}
+`)
+	oas, err := sg.ToOAS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pet := oas.Components.Schemas["Pet"]
+	if pet.Description != "Pets are listed by the store." || pet.Comment != "Kept in sync with the store." {
+		t.Errorf("expected the description and comment of Pet, got %q and %q", pet.Description, pet.Comment)
+	}
+	if name := pet.Properties["name"]; name.Description != "The name the pet answers to." || name.Comment != "Not unique." {
+		t.Errorf("expected the description and comment of name, got %q and %q", name.Description, name.Comment)
+	}
+	b, err := json.Marshal(oas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := NewSchemaGen()
+	again.EmitComments = true
+	if err := again.AddFromReader(bytes.NewReader(b), "pets.json", FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := again.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	before := render(t, sg)
+	assertContains(t, before, "// Pets are listed by the store.", "// Kept in sync with the store.", "// Not unique.")
+	if after := render(t, again); before != after {
+		t.Errorf("round trip changed the source\nbefore:\n%s\nafter:\n%s\nthrough:\n%s", before, after, b)
+	}
+}
@@ -0,0 +1,81 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// renderPresence is the presence bitset of a struct, a bit per optional field.
+type renderPresence struct {
+	Words  int // Length of the bitset in 64-bit words
+	Fields []presentField
+}
+
+// presentField is an optional field tracked by the presence bitset.
+type presentField struct {
+	Name string
+	Key  string // JSON key
+	Word int
+	Mask string // Bit of the field within its word
+}
+
+// declarePresence adds the presence bitset of the optional fields to the structs along with the Has and Set
+// methods of each field. Structs that embed or are embedded, or keep the unknown keys, are left out as their JSON
+// methods would clash, which is reported: their optional zero values are omitted.
+func (r *renderer) declarePresence() error {
+	embedded, embedding := r.embeddings()
+	for i := range r.types {
+		rt := &r.types[i]
+		var fields []presentField
+		for _, f := range rt.Fields {
+			if key, _ := jsonKey(f); f.Optional && !f.Embedded && key != "" {
+				n := len(fields)
+				fields = append(fields, presentField{Name: f.Name, Key: key, Word: n / 64, Mask: "1 << " + strconv.Itoa(n%64)})
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if embedded[rt.Name] || embedding[rt.Name] || rt.Extra || rt.Additional != "" {
+			if r.sg.Diagnostics != nil {
+				r.sg.Diagnostics.Warn(rt.Name, "struct does not track the presence of its optional fields as it embeds, is embedded or declares JSON methods")
+			}
+			continue
+		}
+		rt.Presence = &renderPresence{Words: (len(fields) + 63) / 64, Fields: fields}
+		for _, pf := range fields {
+			f := rt.Fields[fieldIndex(*rt, pf.Name)]
+			for _, name := range []string{"Has" + f.Name, "Set" + f.Name} {
+				if hasField(*rt, name) {
+					return fmt.Errorf("schema %s: method %s of %s clashes with its field %s, rename %s with %s",
+						rt.Schema, name, rt.Name, name, name, XGoName)
+				}
+			}
+			bit := "v.present[" + strconv.Itoa(pf.Word) + "]"
+			rt.Methods = append(rt.Methods,
+				renderMethod{
+					Doc:       []string{"Has" + f.Name + " reports whether " + f.Name + " was present in the decoded JSON, null included, or set by Set" + f.Name + "."},
+					Signature: "(v " + rt.Name + ") Has" + f.Name + "() bool",
+					Body:      []string{"return " + bit + "&(" + pf.Mask + ") != 0"},
+				},
+				renderMethod{
+					Doc:       []string{"Set" + f.Name + " sets " + f.Name + " and marks it present, so that it is encoded even when zero."},
+					Signature: "(v *" + rt.Name + ") Set" + f.Name + "(value " + f.Type + ")",
+					Body:      []string{"v." + f.Name + " = value", bit + " |= " + pf.Mask},
+				})
+		}
+		r.schema = rt.Schema
+		r.addImport("encoding/json")
+	}
+	return nil
+}
+
+// fieldIndex returns the index of the struct field with the given name, -1 if there is none.
+func fieldIndex(rt renderType, name string) int {
+	for i, f := range rt.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
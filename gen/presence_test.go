@@ -0,0 +1,90 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPresence(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.Presence = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        nick: {type: string}
+        count: {type: integer}
+        good: {type: boolean}
+        tags:
+          type: array
+          items: {type: string}
+        owner:
+          type: object
+          properties:
+            email: {type: string}
+    Base:
+      type: object
+      properties:
+        id: {type: string}
+    Dog:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            bark: {type: boolean}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Count int64 `json:\"count,omitempty\"`", "present [1]uint64",
+		"func (v Pet) HasCount() bool {", "func (v *Pet) SetNick(value string) {")
+	if strings.Contains(source, "func (v Pet) HasName") {
+		t.Errorf("expected no presence of the required field\n%s", source)
+	}
+	var warned []string
+	for _, w := range sg.Diagnostics.Warnings {
+		warned = append(warned, w.Field)
+	}
+	if strings.Join(warned, ",") != "Base,Dog" {
+		t.Errorf("expected warnings for Base and Dog, got %v", sg.Diagnostics.Warnings)
+	}
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var p Pet
+	if err := json.Unmarshal([]byte(` + "`" + `{"name":"fido","count":0,"good":false,"tags":null}` + "`" + `), &p); err != nil {
+		panic(err)
+	}
+	fmt.Println(p.HasCount(), p.HasGood(), p.HasTags(), p.HasNick(), p.HasOwner())
+	b, err := json.Marshal(p)
+	fmt.Println(string(b), err)
+
+	if err := json.Unmarshal([]byte(` + "`" + `{"name":"rex","nick":"r"}` + "`" + `), &p); err != nil {
+		panic(err)
+	}
+	fmt.Println(p.HasCount(), p.HasNick())
+	p.Nick = ""
+	p.SetCount(0)
+	b, err = json.Marshal(p)
+	fmt.Println(string(b), err)
+}
+`,
+	})
+	want := `true true true false false
+{"count":0,"good":false,"name":"fido","owner":{},"tags":null} <nil>
+false true
+{"count":0,"name":"rex","nick":"","owner":{}} <nil>
+`
+	if out != want {
+		t.Errorf("expected\n%s\ngot\n%s\n%s", want, out, source)
+	}
+}
@@ -0,0 +1,1483 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NilMapMode selects how optional map fields are encoded in JSON when they are nil.
+type NilMapMode int
+
+const (
+	// NilMapOmit omits nil and empty maps from the encoding.
+	NilMapOmit NilMapMode = iota
+	// NilMapNull encodes nil maps as null and empty maps as {}.
+	NilMapNull
+	// NilMapEmpty encodes nil maps as {}.
+	NilMapEmpty
+)
+
+// formatType is the Go type of a string format and the package that declares it.
+type formatType struct {
+	Type   string
+	Import string
+}
+
+// stringFormatTypes maps the string formats that are not rendered as string to their Go type.
+var stringFormatTypes = map[string]formatType{
+	"date":      {Type: "time.Time", Import: "time"},
+	"date-time": {Type: "time.Time", Import: "time"},
+	"byte":      {Type: "[]byte"},
+	"binary":    {Type: "[]byte"},
+}
+
+// renderFile is the data of the file template.
+type renderFile struct {
+	Header  string // Comment preceding the package clause
+	Package string
+	Imports []string
+	Types   []renderType
+	Catalog []string // Names of the types listed by the catalog
+	Helpers string   // Source of the unexported functions shared by the types
+}
+
+const (
+	// CatalogFile is the name of the file WriteToDir writes the catalog to.
+	CatalogFile = "catalog.go"
+	// DocFile is the name of the file WriteToDir writes the package comment to.
+	DocFile = "doc.go"
+)
+
+// renderDoc is the data of the doc template.
+type renderDoc struct {
+	Header      string
+	Package     string
+	Documents   []string
+	Types       int
+	GeneratedAt string
+}
+
+var docTemplate = template.Must(template.New("doc").Parse(`{{with .Header}}// {{.}}
+
+{{end -}}
+// Package {{.Package}} holds the Go types generated from the schemas
+{{- with .Documents}} of {{range $i, $doc := .}}{{if $i}}, {{end}}{{$doc}}{{end}}{{end}}.
+//
+// It declares {{.Types}} types.
+{{- with .GeneratedAt}}
+// Generated at {{.}}.
+{{- end}}
+package {{.Package}}
+`))
+
+// renderType is a Go type declaration. Structs carry Fields, enums carry Consts and all other types are
+// declared with their Underlying type.
+type renderType struct {
+	Name       string
+	Doc        []string // Lines of the doc comment
+	Comment    string
+	Underlying string
+	Fields     []renderField
+	Consts     []renderConst
+	NilAsEmpty bool   // Map types encoding nil as {}
+	SQL        bool   // Types stored as JSON columns through database/sql
+	Extra      bool   // Structs keeping the unknown JSON keys in Extra
+	Additional string // Type of the values of the AdditionalProperties of the structs decoding them
+	JSONKeys   []string
+	Schema     string   // Name of the top level schema declaring the type
+	Implements []string // Qualified names of the interfaces the type is asserted to implement
+	EasyJSON   bool     // Structs marked for easyjson
+	JSONFields []jsonField
+	Methods    []renderMethod
+	Presence   *renderPresence // Structs tracking the presence of their optional fields
+}
+
+// renderMethod is a method declared along with a type, or a function when it has no receiver. The body is
+// formatted by go/format.
+type renderMethod struct {
+	Doc       []string // Lines of the doc comment
+	Signature string   // Receiver, name, parameters and results
+	Body      []string // Statements
+}
+
+// jsonField is a JSON key of a struct and the name of the Go field it is decoded into.
+type jsonField struct {
+	Key   string
+	Field string
+}
+
+type renderField struct {
+	Name     string
+	Type     string
+	Doc      []string // Lines of the doc comment
+	Comment  string
+	Tag      string // Struct tag including the enclosing back quotes
+	Embedded bool
+	Optional bool // Field of a member that is not required
+}
+
+type renderConst struct {
+	Name  string
+	Type  string   // Type of the constant when it is not the type declaring it
+	Doc   []string // Lines of the doc comment
+	Value string
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`{{with .Header}}// {{.}}
+
+{{end -}}
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{end}}
+{{- range .Types}}{{$type := .Name}}
+{{with .Comment}}// {{.}}
+
+{{end -}}
+{{range .Doc}}//{{with .}} {{.}}{{end}}
+{{end -}}
+{{if .EasyJSON}}//easyjson:json
+{{end -}}
+{{if .Fields}}type {{.Name}} struct {
+{{- range .Fields}}
+{{- range .Doc}}
+	//{{with .}} {{.}}{{end}}
+{{- end}}
+	{{if .Embedded}}{{.Type}}{{else}}{{.Name}} {{.Type}}{{with .Tag}} {{.}}{{end}}{{end}}{{with .Comment}} // {{.}}{{end}}
+{{- end}}
+{{- with .Presence}}
+
+	present [{{.Words}}]uint64 // Bits of the optional fields present
+{{- end}}
+}
+{{else}}type {{.Name}} {{.Underlying}}
+{{end -}}
+{{- range .Implements}}
+var _ {{.}} = (*{{$type}})(nil)
+{{end -}}
+{{with .JSONFields}}
+// {{$type}}JSONFields maps the JSON keys of {{$type}} to the names of its fields.
+var {{$type}}JSONFields = map[string]string{
+{{- range .}}
+	{{printf "%q" .Key}}: {{printf "%q" .Field}},
+{{- end}}
+}
+{{end -}}
+{{if .Consts}}
+const (
+{{- range .Consts}}
+{{- range .Doc}}
+	//{{with .}} {{.}}{{end}}
+{{- end}}
+	{{.Name}} {{with .Type}}{{.}}{{else}}{{$type}}{{end}} = {{.Value}}
+{{- end}}
+)
+{{end -}}
+{{if .NilAsEmpty}}
+// MarshalJSON encodes a nil {{.Name}} as an empty object.
+func (m {{.Name}}) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal({{.Underlying}}(m))
+}
+{{end -}}
+{{if .Extra}}
+// UnmarshalJSON decodes the fields of the {{.Name}} and keeps the other keys in Extra.
+func (v *{{.Name}}) UnmarshalJSON(b []byte) error {
+	type plain {{.Name}}
+	if err := json.Unmarshal(b, (*plain)(v)); err != nil {
+		return err
+	}
+	var extra map[string]json.RawMessage
+	if err := json.Unmarshal(b, &extra); err != nil {
+		return err
+	}
+{{- range .JSONKeys}}
+	delete(extra, {{printf "%q" .}})
+{{- end}}
+	v.Extra = nil
+	if len(extra) > 0 {
+		v.Extra = extra
+	}
+	return nil
+}
+
+// MarshalJSON encodes the fields of the {{.Name}} along with the keys of Extra.
+func (v {{.Name}}) MarshalJSON() ([]byte, error) {
+	type plain {{.Name}}
+	b, err := json.Marshal(plain(v))
+	if err != nil || len(v.Extra) == 0 {
+		return b, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for k, raw := range v.Extra {
+		if _, ok := all[k]; !ok {
+			all[k] = raw
+		}
+	}
+	return json.Marshal(all)
+}
+{{end -}}
+{{if .Additional}}
+// UnmarshalJSON decodes the fields of the {{.Name}} and the other keys into AdditionalProperties.
+func (v *{{.Name}}) UnmarshalJSON(b []byte) error {
+	type plain {{.Name}}
+	if err := json.Unmarshal(b, (*plain)(v)); err != nil {
+		return err
+	}
+	var additional map[string]json.RawMessage
+	if err := json.Unmarshal(b, &additional); err != nil {
+		return err
+	}
+{{- range .JSONKeys}}
+	delete(additional, {{printf "%q" .}})
+{{- end}}
+	v.AdditionalProperties = nil
+	for k, raw := range additional {
+		var value {{.Additional}}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if v.AdditionalProperties == nil {
+			v.AdditionalProperties = make(map[string]{{.Additional}}, len(additional))
+		}
+		v.AdditionalProperties[k] = value
+	}
+	return nil
+}
+
+// MarshalJSON encodes the fields of the {{.Name}} along with its AdditionalProperties.
+func (v {{.Name}}) MarshalJSON() ([]byte, error) {
+	type plain {{.Name}}
+	b, err := json.Marshal(plain(v))
+	if err != nil || len(v.AdditionalProperties) == 0 {
+		return b, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for k, value := range v.AdditionalProperties {
+		if _, ok := all[k]; ok {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		all[k] = raw
+	}
+	return json.Marshal(all)
+}
+{{end -}}
+{{with .Presence}}
+// UnmarshalJSON decodes the fields of the {{$type}} and records which of its optional fields are present.
+func (v *{{$type}}) UnmarshalJSON(b []byte) error {
+	type plain {{$type}}
+	if err := json.Unmarshal(b, (*plain)(v)); err != nil {
+		return err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return err
+	}
+	v.present = [{{.Words}}]uint64{}
+{{- range .Fields}}
+	if _, ok := keys[{{printf "%q" .Key}}]; ok {
+		v.present[{{.Word}}] |= {{.Mask}}
+	}
+{{- end}}
+	return nil
+}
+
+// MarshalJSON encodes the fields of the {{$type}}, the optional fields present included even when they are zero.
+func (v {{$type}}) MarshalJSON() ([]byte, error) {
+	type plain {{$type}}
+	b, err := json.Marshal(plain(v))
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	n := len(all)
+{{- range .Fields}}
+	if _, ok := all[{{printf "%q" .Key}}]; !ok && v.present[{{.Word}}]&({{.Mask}}) != 0 {
+		if all[{{printf "%q" .Key}}], err = json.Marshal(v.{{.Name}}); err != nil {
+			return nil, err
+		}
+	}
+{{- end}}
+	if len(all) == n {
+		return b, nil
+	}
+	return json.Marshal(all)
+}
+{{end -}}
+{{if .SQL}}
+// Value encodes the {{.Name}} as JSON for database/sql.
+func (v {{.Name}}) Value() (driver.Value, error) {
+	return json.Marshal(v)
+}
+
+// Scan decodes the {{.Name}} from a JSON column.
+func (v *{{.Name}}) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(s, v)
+	case string:
+		return json.Unmarshal([]byte(s), v)
+	}
+	return fmt.Errorf("cannot scan %T into {{.Name}}", src)
+}
+{{end -}}
+{{range .Methods}}
+{{range .Doc}}//{{with .}} {{.}}{{end}}
+{{end -}}
+func {{.Signature}} {
+{{- range .Body}}
+	{{.}}
+{{- end}}
+}
+{{end -}}
+{{end -}}
+{{with .Catalog}}
+// Schemas maps the names of the generated types to their reflect.Type.
+var Schemas = map[string]reflect.Type{
+{{- range .}}
+	{{printf "%q" .}}: reflect.TypeOf((*{{.}})(nil)).Elem(),
+{{- end}}
+}
+{{end -}}
+{{.Helpers}}
+`))
+
+// Render writes the Go declarations of all the generated schemas to w as a single file of package pkg.
+// Objects are declared as structs, inline objects and enums as types of their own named after the field and
+// prefixed with the enclosing type, references use the name of the referenced type and all other fields their Type.
+func (sg SchemaGen) Render(w io.Writer, pkg string) error {
+	r, names, err := sg.declareAll()
+	if err != nil {
+		return err
+	}
+	f := r.file(pkg, names...)
+	f.Header = sg.header(names...)
+	if sg.Catalog {
+		f.Catalog = r.typeNames()
+		f.Imports = append(f.Imports, "reflect")
+	}
+	if sg.AppendJSON {
+		f.Helpers += jsonHelpers
+		f.Imports = append(f.Imports, jsonImports...)
+	}
+	if sg.EmbedSchemas {
+		f.Helpers += validateHelpers
+		f.Imports = append(f.Imports, "errors")
+	}
+	// go/format drops the duplicate imports
+	sort.Strings(f.Imports)
+	b, err := sg.source(fileTemplate, f)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// WriteToDir writes the Go declarations of each generated schema to a file of its own in dir named after the
+// schema, unless MaxTypesPerFile is set. The directory is created if needed and existing files are overwritten.
+// A type already declared by a hand-written file of dir fails the generation, or is left out under
+// KeepExistingTypes. The first error encountered is returned.
+func (sg SchemaGen) WriteToDir(dir, pkg string) error {
+	r, names, err := sg.declareAll()
+	if err != nil {
+		return err
+	}
+	files, schemas, err := sg.files(r, pkg, names)
+	if err != nil {
+		return err
+	}
+	written := make(map[string]bool)
+	for _, file := range append(files, CatalogFile, DocFile, JSONFile, ValidateFile) {
+		written[file] = schemas[file] != nil || sg.writes(file)
+	}
+	kept, err := sg.keepExisting(r, dir, written)
+	if err != nil {
+		return err
+	}
+	if kept {
+		if files, schemas, err = sg.files(r, pkg, names); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		f := r.file(pkg, schemas[file]...)
+		if kept {
+			if len(f.Types) == 0 {
+				continue
+			}
+			if f.Imports, err = sg.usedImports(f); err != nil {
+				return err
+			}
+		}
+		f.Header = sg.header(schemas[file]...)
+		b, err := sg.source(fileTemplate, f)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", strings.Join(schemas[file], ", "), err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, file), b, 0644); err != nil {
+			return err
+		}
+	}
+	if sg.Catalog {
+		b, err := sg.source(fileTemplate, renderFile{Header: sg.header(names...), Package: pkg, Imports: []string{"reflect"},
+			Catalog: r.typeNames()})
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, CatalogFile), b, 0644); err != nil {
+			return err
+		}
+	}
+	if sg.AppendJSON {
+		b, err := sg.source(fileTemplate, renderFile{Header: sg.header(names...), Package: pkg, Imports: jsonImports,
+			Helpers: jsonHelpers})
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, JSONFile), b, 0644); err != nil {
+			return err
+		}
+	}
+	if sg.EmbedSchemas {
+		b, err := sg.source(fileTemplate, renderFile{Header: sg.header(names...), Package: pkg, Imports: []string{"errors"},
+			Helpers: validateHelpers})
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, ValidateFile), b, 0644); err != nil {
+			return err
+		}
+	}
+	if sg.FuzzCorpus {
+		if err := sg.writeCorpus(r, dir, names); err != nil {
+			return err
+		}
+	}
+	if sg.PackageDoc {
+		return sg.writeDoc(filepath.Join(dir, DocFile), pkg, names, len(r.types))
+	}
+	return nil
+}
+
+// files returns the names of the files WriteToDir writes the schemas to, in order, along with the schemas of each
+// file. A file named after a schema must not clash with the other files written.
+func (sg SchemaGen) files(r *renderer, pkg string, names []string) ([]string, map[string][]string, error) {
+	var files []string
+	schemas := make(map[string][]string)
+	if sg.MaxTypesPerFile > 0 {
+		count := make(map[string]int)
+		for _, rt := range r.types {
+			count[rt.Schema]++
+		}
+		types := 0
+		for _, name := range names {
+			if len(files) == 0 || types+count[name] > sg.MaxTypesPerFile {
+				files = append(files, pkg+"_"+strconv.Itoa(len(files)+1)+".go")
+				types = 0
+			}
+			file := files[len(files)-1]
+			schemas[file] = append(schemas[file], name)
+			types += count[name]
+		}
+		return files, schemas, nil
+	}
+	for _, name := range names {
+		file := getFieldName(sg.names(), name) + ".go"
+		// Compared regardless of case as file systems may be case insensitive
+		for _, reserved := range []string{CatalogFile, DocFile, JSONFile, ValidateFile} {
+			if strings.EqualFold(file, reserved) && sg.writes(reserved) {
+				return nil, nil, fmt.Errorf("schema %s: file %s clashes with the %s, rename %s with %s", name, file, reserved, name, XGoName)
+			}
+		}
+		files = append(files, file)
+		schemas[file] = []string{name}
+	}
+	return files, schemas, nil
+}
+
+// writes reports whether WriteToDir writes the given file apart from those of the schemas.
+func (sg SchemaGen) writes(file string) bool {
+	switch file {
+	case CatalogFile:
+		return sg.Catalog
+	case DocFile:
+		return sg.PackageDoc
+	case JSONFile:
+		return sg.AppendJSON
+	case ValidateFile:
+		return sg.EmbedSchemas
+	}
+	return false
+}
+
+// writeDoc writes the package comment of the schemas to file, stating the number of types declared.
+func (sg SchemaGen) writeDoc(file, pkg string, schemas []string, types int) error {
+	doc := renderDoc{Header: sg.header(schemas...), Package: pkg, Documents: sg.documents(schemas...), Types: types}
+	if !sg.GeneratedAt.IsZero() {
+		doc.GeneratedAt = sg.GeneratedAt.UTC().Format(time.RFC3339)
+	}
+	b, err := sg.source(docTemplate, doc)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0644)
+}
+
+// source executes the template with data, formats the result with go/format and applies the PostProcess hook.
+// All the generated source goes through it. A formatting failure is a generator bug, the error carries the
+// unformatted source.
+func (sg SchemaGen) source(t *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("unable to format the generated source: %w\n%s", err, buf.Bytes())
+	}
+	if sg.PostProcess != nil {
+		if b, err = sg.PostProcess(b); err != nil {
+			return nil, fmt.Errorf("post processing failed: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// declareAll declares the types of all the generated schemas and returns the names of the schemas in order.
+func (sg SchemaGen) declareAll() (*renderer, []string, error) {
+	var names []string
+	for k := range sg.SchemaInfos {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	r := sg.newRenderer()
+	for _, name := range names {
+		si := sg.SchemaInfos[name]
+		v, ok := si.Fields[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("schema %s has not been generated", name)
+		}
+		r.schema = name
+		if err := r.declare(v, si.XmlPrefixes); err != nil {
+			return nil, nil, err
+		}
+	}
+	r.checkAdditional()
+	if sg.UnknownFields {
+		r.declareExtra()
+	}
+	if sg.Presence {
+		if err := r.declarePresence(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if sg.EasyJSON {
+		r.markEasyJSON()
+	}
+	if sg.AppendJSON {
+		r.declareAppendJSON()
+	}
+	if sg.JSONFieldMaps {
+		if err := r.declareJSONFields(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if sg.DomainModels {
+		if err := r.declareModels(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if sg.EqualMethods {
+		r.declareEqual()
+	}
+	if sg.TextMethods {
+		r.declareTextMethods()
+	}
+	if sg.EmbedSchemas {
+		if err := r.declareSchemas(names); err != nil {
+			return nil, nil, err
+		}
+	}
+	if sg.Catalog && r.declared["Schemas"] {
+		return nil, nil, fmt.Errorf("type Schemas clashes with the catalog, rename it with %s", XGoName)
+	}
+	return r, names, nil
+}
+
+// renderer collects the type declarations of the schemas in declaration order.
+type renderer struct {
+	sg       SchemaGen
+	types    []renderType
+	declared map[string]bool
+	imports  map[string]map[string]bool // [schema][import path]
+	schema   string                     // Top level schema being declared
+}
+
+func (sg SchemaGen) newRenderer() *renderer {
+	return &renderer{sg: sg, declared: make(map[string]bool), imports: make(map[string]map[string]bool)}
+}
+
+// file returns the file declaring the types of the given schemas along with the packages they use.
+func (r *renderer) file(pkg string, schemas ...string) renderFile {
+	f := renderFile{Package: pkg}
+	imports := make(map[string]bool)
+	for _, schema := range schemas {
+		for k := range r.imports[schema] {
+			imports[k] = true
+		}
+	}
+	for k := range imports {
+		f.Imports = append(f.Imports, k)
+	}
+	sort.Strings(f.Imports)
+	for _, rt := range r.types {
+		for _, schema := range schemas {
+			if rt.Schema == schema {
+				f.Types = append(f.Types, rt)
+				break
+			}
+		}
+	}
+	return f
+}
+
+// declare adds the declaration of a top level schema and of the types it declares inline. The XML namespaces
+// used by the schema are declared as attributes of its struct.
+func (r *renderer) declare(v interface{}, xmlPrefixes map[string]string) error {
+	f := fieldOf(v)
+	switch t := v.(type) {
+	case ObjectField:
+		if err := r.declareStruct(t); err != nil {
+			return err
+		}
+		r.declareNamespaces(t.Type, xmlPrefixes)
+		if r.sg.SQLMethods {
+			r.typeNamed(t.Type).SQL = true
+			r.addImport("database/sql/driver")
+			r.addImport("encoding/json")
+			r.addImport("fmt")
+		}
+		return nil
+	case EnumField:
+		return r.declareEnum(t)
+	}
+	return r.add(renderType{Name: f.Name, Doc: r.typeDoc(f.Name, f), Comment: r.comment(f), Implements: r.implements(f), Underlying: r.typeOf(v)})
+}
+
+func (r *renderer) add(rt renderType) error {
+	if r.declared[rt.Name] {
+		return fmt.Errorf("schema %s: type %s is declared more than once, rename one of them with %s", r.schema, rt.Name, XGoName)
+	}
+	r.declared[rt.Name] = true
+	rt.Schema = r.schema
+	r.types = append(r.types, rt)
+	return nil
+}
+
+func (r *renderer) declareStruct(o ObjectField) error {
+	// The struct is added before its inline types so that they follow it in the output
+	index := len(r.types)
+	if err := r.add(renderType{Name: o.Type, Doc: r.typeDoc(o.Type, o.Field), Comment: r.comment(o.Field), Implements: r.implements(o.Field), Underlying: "struct{}"}); err != nil {
+		return err
+	}
+	o, inherited := r.flatten(o)
+	fieldNames := r.fieldNames(o)
+	var names []string
+	for k := range o.Members {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var fields []renderField
+	for _, k := range names {
+		member := o.Members[k]
+		mf := fieldOf(member)
+		if mf.Name != fieldNames[k] {
+			if r.sg.Diagnostics != nil {
+				r.sg.Diagnostics.Warn(mf.TargetNames[JsonContentType], "field %s of %s is renamed %s as the name is taken", mf.Name, o.Type, fieldNames[k])
+			}
+			mf.Name = fieldNames[k]
+		}
+		if r.isMap(member) && r.sg.NilMaps != NilMapOmit && mf.OmitEmpty == nil {
+			// omitempty would drop the nil and empty maps that have to be encoded
+			omitEmpty := false
+			mf.OmitEmpty = &omitEmpty
+		}
+		field := renderField{Name: mf.Name, Doc: r.fieldDoc(mf), Comment: r.comment(mf), Type: r.memberType(member), Tag: structTag(mf),
+			Optional: !mf.Required}
+		if ref, ok := member.(RefField); ok && ref.Embedded {
+			field.Embedded = true
+		}
+		fields = append(fields, field)
+		if n, ok := member.(NumberField); ok && n.Const != nil {
+			name := o.Type + mf.Name
+			r.types[index].Consts = append(r.types[index].Consts, renderConst{Name: name, Type: n.Type,
+				Doc: []string{name + " is the only value allowed for " + mf.Name}, Value: strconv.FormatFloat(*n.Const, 'f', -1, 64)})
+		}
+		if !field.Embedded {
+			r.types[index].JSONKeys = append(r.types[index].JSONKeys, mf.TargetNames[JsonContentType])
+			if r.sg.JSONFieldMaps {
+				r.types[index].JSONFields = append(r.types[index].JSONFields, jsonField{Key: mf.TargetNames[JsonContentType], Field: mf.Name})
+			}
+		}
+		if inherited[k] {
+			// Declared along with the base the member is copied from
+			continue
+		}
+		if err := r.declareInline(member); err != nil {
+			return err
+		}
+	}
+	for _, value := range o.AdditionalProperties {
+		mapType := "map[string]" + r.typeOf(value)
+		if len(fields) == 0 {
+			// A free-form object is the map itself
+			r.types[index].Underlying = mapType
+			if r.sg.NilMaps == NilMapEmpty {
+				r.types[index].NilAsEmpty = true
+				r.addImport("encoding/json")
+			}
+		} else {
+			fields = append(fields, renderField{Name: "AdditionalProperties", Type: mapType, Tag: "`json:\"-\"`"})
+			r.types[index].Additional = r.typeOf(value)
+		}
+		if err := r.declareInline(value); err != nil {
+			return err
+		}
+	}
+	r.types[index].Fields = fields
+	return r.addConsts(r.types[index])
+}
+
+// fieldNames returns the names of the struct fields declared for the members of the object, keyed by member.
+// A name taken by an embedded type, by a member sorted before, by the Extra and AdditionalProperties fields
+// added to the struct or by the generated methods gets a numeric suffix.
+func (r *renderer) fieldNames(o ObjectField) map[string]string {
+	taken := make(map[string]bool)
+	if r.sg.UnknownFields {
+		taken["Extra"] = true
+	}
+	if len(o.AdditionalProperties) > 0 {
+		taken["AdditionalProperties"] = true
+	}
+	if r.sg.DomainModels {
+		// Methods of the domain type
+		taken["ToWire"] = true
+		taken["FromWire"] = true
+	}
+	if r.sg.EqualMethods {
+		taken["Equal"] = true
+	}
+	if r.sg.AppendJSON {
+		taken["AppendJSON"] = true
+		taken["MarshalJSON"] = true
+	}
+	if r.sg.EmbedSchemas {
+		taken["ValidateJSON"] = true
+	}
+	var keys []string
+	for k, member := range o.Members {
+		if ref, ok := member.(RefField); ok && ref.Embedded {
+			taken[strings.TrimPrefix(r.memberType(member), "*")] = true
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	names := make(map[string]string, len(keys))
+	for _, k := range keys {
+		name := fieldOf(o.Members[k]).Name
+		if ref, ok := o.Members[k].(RefField); !ok || !ref.Embedded {
+			for i := 2; taken[name]; i++ {
+				name = fieldOf(o.Members[k]).Name + strconv.Itoa(i)
+			}
+			taken[name] = true
+		}
+		names[k] = name
+	}
+	return names
+}
+
+// flatten returns the object with the members of its embedded bases copied in place of the bases when a base
+// is embedded through more than one path, as in a diamond where A embeds B and C which both embed D, or when
+// two bases declare the same member, as the oneOf branches Cat and Dog both declaring name. These fields would
+// otherwise be ambiguous selectors of the object, which encoding/json silently ignores. Each base is copied
+// once and the members of the object win over those of its bases. The members copied from a base are returned
+// as inherited, their inline types are declared by the base. Any other object is returned as is.
+func (r *renderer) flatten(o ObjectField) (ObjectField, map[string]bool) {
+	si := r.sg.SchemaInfos[r.schema]
+	if si == nil {
+		return o, nil
+	}
+	ambiguous := false
+	seen := make(map[*SchemaInfo]bool)
+	declaredBy := make(map[string]*SchemaInfo)
+	r.walkBases(si, o, false, make(map[*SchemaInfo]bool), func(base *SchemaInfo, bo ObjectField, _ bool) {
+		ambiguous = ambiguous || seen[base]
+		seen[base] = true
+		for k := range bo.Members {
+			if other, ok := declaredBy[k]; ok && other != base {
+				ambiguous = true
+			}
+			declaredBy[k] = base
+		}
+	})
+	if !ambiguous {
+		return o, nil
+	}
+	r.sg.tracef("copying the members of the bases of %s as some of their fields would be ambiguous", o.Type)
+	members := make(map[string]interface{})
+	for k, member := range o.Members {
+		if ref, ok := member.(RefField); !ok || !ref.Embedded || r.base(si, ref) == nil {
+			members[k] = member
+		}
+	}
+	inherited := make(map[string]bool)
+	copied := make(map[*SchemaInfo]bool)
+	r.walkBases(si, o, false, make(map[*SchemaInfo]bool), func(base *SchemaInfo, bo ObjectField, optional bool) {
+		if copied[base] {
+			return
+		}
+		copied[base] = true
+		for k, member := range bo.Members {
+			if _, ok := members[k]; ok {
+				continue
+			}
+			if ref, ok := member.(RefField); ok && ref.Embedded && r.base(base, ref) != nil {
+				continue
+			}
+			if optional {
+				// The members of an anyOf branch are optional as the branch may not match
+				f := fieldOf(member)
+				f.Required = false
+				member = withField(member, f)
+			}
+			members[k] = member
+			inherited[k] = true
+		}
+	})
+	o.Members = members
+	return o, inherited
+}
+
+// walkBases calls visit for each object schema embedded by o, directly or through the bases it embeds, once
+// per path to the base. optional is set for the bases reached through an anyOf branch.
+func (r *renderer) walkBases(si *SchemaInfo, o ObjectField, optional bool, onPath map[*SchemaInfo]bool,
+	visit func(base *SchemaInfo, bo ObjectField, optional bool)) {
+	var keys []string
+	for k := range o.Members {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ref, ok := o.Members[k].(RefField)
+		if !ok || !ref.Embedded {
+			continue
+		}
+		base := r.base(si, ref)
+		if base == nil || onPath[base] {
+			continue
+		}
+		bo := base.Fields[base.Name].(ObjectField)
+		visit(base, bo, optional || ref.Pointer)
+		onPath[base] = true
+		r.walkBases(base, bo, optional || ref.Pointer, onPath, visit)
+		onPath[base] = false
+	}
+}
+
+// base returns the schema of the embedded reference relative to si, nil unless it is a generated object.
+func (r *renderer) base(si *SchemaInfo, ref RefField) *SchemaInfo {
+	base := r.sg.lookupRef(si.DocPath, ref.Reference)
+	if base == nil {
+		return nil
+	}
+	if _, ok := base.Fields[base.Name].(ObjectField); !ok {
+		return nil
+	}
+	return base
+}
+
+// declareInline adds the declaration of the types declared inline by a member.
+func (r *renderer) declareInline(v interface{}) error {
+	switch t := v.(type) {
+	case ObjectField:
+		return r.declareStruct(t)
+	case EnumField:
+		return r.declareEnum(t)
+	}
+	return nil
+}
+
+// declareNamespaces adds an xmlns attribute field for each prefix to the struct declared as name.
+func (r *renderer) declareNamespaces(name string, xmlPrefixes map[string]string) {
+	var prefixes []string
+	for k := range xmlPrefixes {
+		prefixes = append(prefixes, k)
+	}
+	sort.Strings(prefixes)
+	if rt := r.typeNamed(name); rt != nil {
+		for _, prefix := range prefixes {
+			attr := "xmlns"
+			if prefix != "" {
+				attr += ":" + prefix
+			}
+			rt.Fields = append(rt.Fields, renderField{
+				Name: getFieldName(r.sg.names(), "Xmlns_"+prefix),
+				Type: "string",
+				Doc:  []string{"Declares the namespace " + xmlPrefixes[prefix]},
+				Tag:  "`" + `json:"-" xml:"` + attr + `,attr"` + "`",
+			})
+		}
+	}
+}
+
+// embeddings returns the names of the structs embedded in another one and of the structs embedding another one.
+func (r *renderer) embeddings() (embedded, embedding map[string]bool) {
+	embedded = make(map[string]bool)
+	embedding = make(map[string]bool)
+	for _, rt := range r.types {
+		for _, f := range rt.Fields {
+			if f.Embedded {
+				embedded[strings.TrimPrefix(f.Type, "*")] = true
+				embedding[rt.Name] = true
+			}
+		}
+	}
+	return embedded, embedding
+}
+
+// checkAdditional leaves the structs that embed or are embedded without the JSON methods decoding their
+// AdditionalProperties, as the methods of an embedded type would take over the encoding of the embedding struct.
+// The AdditionalProperties of these structs are not encoded, which is reported.
+func (r *renderer) checkAdditional() {
+	embedded, embedding := r.embeddings()
+	for i := range r.types {
+		rt := &r.types[i]
+		if rt.Additional == "" {
+			continue
+		}
+		if embedded[rt.Name] || embedding[rt.Name] {
+			rt.Additional = ""
+			if r.sg.Diagnostics != nil {
+				r.sg.Diagnostics.Warn(rt.Name, "additional properties are not encoded as the struct embeds or is embedded")
+			}
+			continue
+		}
+		r.schema = rt.Schema
+		r.addImport("encoding/json")
+	}
+}
+
+// declareExtra adds the Extra map to the structs. Structs that embed or are embedded are left out as the
+// JSON methods of an embedded type would take over the encoding of the embedding struct. Structs holding
+// AdditionalProperties already keep all the keys.
+func (r *renderer) declareExtra() {
+	embedded, embedding := r.embeddings()
+	for i := range r.types {
+		rt := &r.types[i]
+		if len(rt.Fields) == 0 || embedded[rt.Name] || embedding[rt.Name] || hasField(*rt, "AdditionalProperties") {
+			continue
+		}
+		rt.Extra = true
+		rt.Fields = append(rt.Fields, renderField{
+			Name: "Extra",
+			Type: "map[string]json.RawMessage",
+			Doc:  []string{"Keeps the JSON keys matching none of the fields"},
+			Tag:  "`json:\"-\"`",
+		})
+		r.schema = rt.Schema
+		r.addImport("encoding/json")
+	}
+}
+
+// markEasyJSON marks the structs for easyjson. The structs declaring JSON methods of their own are left out as
+// easyjson would declare them again, which is reported.
+func (r *renderer) markEasyJSON() {
+	for i := range r.types {
+		rt := &r.types[i]
+		if rt.Underlying != "struct{}" {
+			continue
+		}
+		if ownJSON(*rt) {
+			if r.sg.Diagnostics != nil {
+				r.sg.Diagnostics.Warn(rt.Name, "struct is not marked for easyjson as it declares JSON methods")
+			}
+			continue
+		}
+		rt.EasyJSON = true
+	}
+}
+
+// declareJSONFields completes the JSON keys of each struct with those of the fields promoted from the structs it
+// embeds and declares their map.
+func (r *renderer) declareJSONFields() error {
+	for i := range r.types {
+		rt := &r.types[i]
+		if rt.Underlying != "struct{}" {
+			continue
+		}
+		name := rt.Name + "JSONFields"
+		if r.declared[name] {
+			return fmt.Errorf("schema %s: %s of %s clashes with another declaration, rename %s with %s",
+				rt.Schema, name, rt.Name, rt.Name, XGoName)
+		}
+		r.declared[name] = true
+		rt.JSONFields = r.jsonFields(*rt, map[string]bool{rt.Name: true})
+	}
+	return nil
+}
+
+// jsonFields returns the JSON keys of the struct and of the fields promoted from the structs it embeds, sorted by
+// key. A promoted field is left out when a shallower field has the same key or name.
+func (r *renderer) jsonFields(rt renderType, onPath map[string]bool) []jsonField {
+	fields := append([]jsonField(nil), rt.JSONFields...)
+	keys := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, f := range rt.JSONFields {
+		keys[f.Key] = true
+		names[f.Field] = true
+	}
+	for _, f := range rt.Fields {
+		if !f.Embedded {
+			names[f.Name] = true
+		}
+	}
+	for _, f := range rt.Fields {
+		base := r.typeNamed(strings.TrimPrefix(f.Type, "*"))
+		if !f.Embedded || base == nil || onPath[base.Name] {
+			continue
+		}
+		onPath[base.Name] = true
+		for _, promoted := range r.jsonFields(*base, onPath) {
+			if !keys[promoted.Key] && !names[promoted.Field] {
+				fields = append(fields, promoted)
+				keys[promoted.Key] = true
+			}
+		}
+		onPath[base.Name] = false
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
+
+// hasField reports whether the struct declares a field with the given name.
+func hasField(rt renderType, name string) bool {
+	for _, f := range rt.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// documents returns the paths of the documents declaring the schemas, sorted. The schemas without a document,
+// such as the records, are left out.
+func (sg SchemaGen) documents(schemas ...string) []string {
+	var documents []string
+	seen := make(map[string]bool)
+	for _, name := range schemas {
+		si, ok := sg.SchemaInfos[name]
+		if !ok {
+			continue
+		}
+		if d := si.DocPath.String(); d != "" && !seen[d] {
+			seen[d] = true
+			documents = append(documents, d)
+		}
+	}
+	sort.Strings(documents)
+	return documents
+}
+
+// header returns the comment marking a file declaring the schemas as generated, naming their documents along with
+// their info.version, empty unless the Header is enabled.
+func (sg SchemaGen) header(schemas ...string) string {
+	if !sg.Header {
+		return ""
+	}
+	var sources []string
+	for _, d := range sg.documents(schemas...) {
+		if version := sg.Infos[d].Version; version != "" {
+			d += " version " + version
+		}
+		sources = append(sources, d)
+	}
+	if len(sources) == 0 {
+		return "Code generated by turbo-gen. DO NOT EDIT."
+	}
+	return "Code generated by turbo-gen from " + strings.Join(sources, ", ") + ". DO NOT EDIT."
+}
+
+// typeNames returns the names of the declared types, sorted.
+func (r *renderer) typeNames() []string {
+	names := make([]string, 0, len(r.types))
+	for _, rt := range r.types {
+		names = append(names, rt.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// typeNamed returns the declared type with the given name, nil if there is none.
+func (r *renderer) typeNamed(name string) *renderType {
+	for i := range r.types {
+		if r.types[i].Name == name {
+			return &r.types[i]
+		}
+	}
+	return nil
+}
+
+func (r *renderer) declareEnum(e EnumField) error {
+	rt := renderType{Name: e.Type, Doc: r.typeDoc(e.Type, e.Field), Comment: r.comment(e.Field), Implements: r.implements(e.Field),
+		Underlying: e.BaseType}
+	names := map[string]bool{e.Type: true}
+	for _, v := range e.Values {
+		value := fmt.Sprint(v)
+		if s, ok := v.(string); ok {
+			value = fmt.Sprintf("%q", s)
+		}
+		// Values differing only by the runes dropped from identifiers are told apart by a numeric suffix
+		name := enumConstName(r.sg.names(), e.Type, v)
+		for i := 2; names[name]; i++ {
+			name = enumConstName(r.sg.names(), e.Type, v) + strconv.Itoa(i)
+		}
+		names[name] = true
+		rt.Consts = append(rt.Consts, renderConst{Name: name, Value: value})
+	}
+	if err := r.add(rt); err != nil {
+		return err
+	}
+	return r.addConsts(rt)
+}
+
+// addConsts records the names of the constants declared along with the type, which must not clash with the
+// other declarations.
+func (r *renderer) addConsts(rt renderType) error {
+	for _, c := range rt.Consts {
+		if r.declared[c.Name] {
+			return fmt.Errorf("schema %s: constant %s of %s clashes with another declaration, rename %s with %s",
+				r.schema, c.Name, rt.Name, rt.Name, XGoName)
+		}
+		r.declared[c.Name] = true
+	}
+	return nil
+}
+
+// enumConstName returns the name of the constant of the enum value v, the name of the enum type followed by the
+// value. The empty string is named Empty, the sign of negative numbers Minus and decimal points _.
+func enumConstName(ns NameStrategy, typeName string, v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		if value == "" {
+			return typeName + "Empty"
+		}
+		return toIdentifier(typeName + ns.FieldName(value))
+	case float64:
+		number := strconv.FormatFloat(value, 'f', -1, 64)
+		if strings.HasPrefix(number, "-") {
+			number = "Minus" + number[1:]
+		}
+		return typeName + strings.Replace(number, ".", "_", 1)
+	}
+	return getFieldName(ns, typeName+"_"+fmt.Sprint(v))
+}
+
+// memberType returns the Go type of the struct field declared for the member. Optional scalars and nullable
+// values are pointers unless their type can already hold nil.
+func (r *renderer) memberType(member interface{}) string {
+	typ := r.typeOf(member)
+	if ref, ok := member.(RefField); ok && ref.Embedded {
+		return typ
+	}
+	mf := fieldOf(member)
+	optional := !r.sg.OptionalValues && !r.sg.Presence && !mf.Required && isScalar(member)
+	if (optional || mf.Nullable) && !r.isNilable(member, typ) {
+		// A pointer tells an absent or null value apart from the zero value
+		return "*" + typ
+	}
+	return typ
+}
+
+// typeOf returns the Go type of the field.
+func (r *renderer) typeOf(v interface{}) string {
+	f := fieldOf(v)
+	var typ string
+	switch t := v.(type) {
+	case StringField:
+		typ = "string"
+		if t.Format != nil {
+			if ft, ok := stringFormatTypes[*t.Format]; ok {
+				typ = ft.Type
+				if ft.Import != "" {
+					r.addImport(ft.Import)
+				}
+			}
+		}
+	case BooleanField:
+		typ = "bool"
+	case ObjectField:
+		typ = t.Type
+	case RefField:
+		typ = t.TypeName
+		if typ == "" {
+			typ = r.refTypeName(t.Reference)
+		}
+		if t.Pointer {
+			typ = "*" + typ
+		}
+	default:
+		typ = f.Type
+	}
+	if strings.HasPrefix(typ, "json.") {
+		r.addImport("encoding/json")
+	}
+	if f.IsArray {
+		typ = "[]" + typ
+	}
+	return typ
+}
+
+// goType is a type expression of the generated source: a pointer, a slice or a map of its Elem, or the name of a
+// declared or predeclared type.
+type goType struct {
+	Prefix string // "*", "[]" or "map[string]", empty for a name
+	Elem   *goType
+	Name   string
+}
+
+// parseType parses the type expressions returned by typeOf and memberType.
+func parseType(s string) *goType {
+	for _, prefix := range []string{"*", "[]", "map[string]"} {
+		if strings.HasPrefix(s, prefix) {
+			return &goType{Prefix: prefix, Elem: parseType(s[len(prefix):])}
+		}
+	}
+	return &goType{Name: s}
+}
+
+// format returns the type expression with each name replaced by rename.
+func (t *goType) format(rename func(string) string) string {
+	if t.Elem != nil {
+		return t.Prefix + t.Elem.format(rename)
+	}
+	return rename(t.Name)
+}
+
+// DefaultCommentWidth is the width at which the lines of doc comments are wrapped unless configured otherwise.
+const DefaultCommentWidth = 80
+
+// typeDoc returns the doc comment of the type declared for the field. The title is prefixed with the name of
+// the type and is followed by the description.
+func (r *renderer) typeDoc(name string, f Field) []string {
+	var doc []string
+	if f.Title != "" {
+		doc = append(doc, name+" "+f.Title)
+	}
+	return r.appendDescription(doc, f.Description)
+}
+
+// fieldDoc returns the doc comment of a struct field, its title followed by its description.
+func (r *renderer) fieldDoc(f Field) []string {
+	var doc []string
+	if f.Title != "" {
+		doc = append(doc, f.Title)
+	}
+	return r.appendDescription(doc, f.Description)
+}
+
+// appendDescription appends the wrapped description to the doc, separated by an empty line.
+func (r *renderer) appendDescription(doc []string, description string) []string {
+	if strings.TrimSpace(description) == "" {
+		return doc
+	}
+	if len(doc) > 0 {
+		doc = append(doc, "")
+	}
+	width := r.sg.CommentWidth
+	if width <= 0 {
+		width = DefaultCommentWidth
+	}
+	// The width includes the "// " comment marker
+	return append(doc, wrapComment(strings.TrimSpace(description), width-len("// "))...)
+}
+
+// wrapComment splits text into lines no longer than width on word boundaries. Line breaks of the text are
+// kept and words longer than width are left on a line of their own.
+func wrapComment(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// comment returns the $comment of the field on a single line if comments are emitted.
+func (r *renderer) comment(f Field) string {
+	if !r.sg.EmitComments {
+		return ""
+	}
+	return strings.Join(strings.Fields(f.Comment), " ")
+}
+
+// implements returns the interfaces the type of the field implements, qualified by the name of their package.
+// Interfaces given as import/path.Name are imported, unqualified ones are expected in the generated package.
+func (r *renderer) implements(f Field) []string {
+	var names []string
+	for _, i := range f.Implements {
+		dot := strings.LastIndex(i, ".")
+		if dot <= strings.LastIndex(i, "/") {
+			names = append(names, i)
+			continue
+		}
+		path := i[:dot]
+		r.addImport(path)
+		names = append(names, path[strings.LastIndex(path, "/")+1:]+i[dot:])
+	}
+	return names
+}
+
+// addImport records that the schema being declared uses the package at path.
+func (r *renderer) addImport(path string) {
+	if r.imports[r.schema] == nil {
+		r.imports[r.schema] = make(map[string]bool)
+	}
+	r.imports[r.schema][path] = true
+}
+
+// isScalar reports whether the field holds a single string, number, boolean or enum value.
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case StringField, NumberField, BooleanField, EnumField:
+		return !fieldOf(v).IsArray
+	}
+	return false
+}
+
+// isNilable reports whether a field of the given Go type can already hold nil.
+func (r *renderer) isNilable(v interface{}, goType string) bool {
+	switch {
+	case r.isMap(v), goType == "interface{}", goType == "json.RawMessage":
+		return true
+	}
+	return strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "*")
+}
+
+// isMap reports whether the field is an object declared as a map type, or a reference to one.
+func (r *renderer) isMap(v interface{}) bool {
+	o, ok := r.target(v).(ObjectField)
+	return ok && len(o.Members) == 0 && len(o.AdditionalProperties) > 0
+}
+
+// target returns the field of the schema a single valued reference points to, following the references between
+// schemas. Any other field, or a reference that does not resolve, is returned as is.
+func (r *renderer) target(v interface{}) interface{} {
+	si := r.sg.SchemaInfos[r.schema]
+	// Bounded by the number of schemas in case the references form a cycle
+	for i := 0; i <= len(r.sg.SchemaInfos) && si != nil; i++ {
+		ref, ok := v.(RefField)
+		if !ok || ref.IsArray || ref.Pointer {
+			return v
+		}
+		if si = r.sg.lookupRef(si.DocPath, ref.Reference); si == nil {
+			return v
+		}
+		t, ok := si.Fields[si.Name]
+		if !ok {
+			return v
+		}
+		v = t
+	}
+	return v
+}
+
+// refTypeName returns the name of the type generated for the schema at ref.
+func (r *renderer) refTypeName(ref string) string {
+	name := refName(ref)
+	if si, ok := r.sg.SchemaInfos[name]; ok {
+		if v, ok := si.Fields[name]; ok {
+			return fieldOf(v).Name
+		}
+	}
+	return getFieldName(r.sg.names(), name)
+}
+
+// structTag returns the struct tag of the field, wrapped in back quotes. Each content type the field has a
+// target name for gets its own key so that the names may differ between them.
+func structTag(f Field) string {
+	tags := []string{jsonTag(f)}
+	if _, ok := f.TargetNames[XmlContentType]; ok {
+		tags = append(tags, xmlTag(f))
+	}
+	return "`" + strings.Join(tags, " ") + "`"
+}
+
+// jsonTag returns the json tag of the field using its JSON property name. Optional fields are tagged omitempty
+// unless x-go-omitempty says otherwise.
+func jsonTag(f Field) string {
+	omitEmpty := !f.Required
+	if f.OmitEmpty != nil {
+		omitEmpty = *f.OmitEmpty
+	}
+	tag := f.TargetNames[JsonContentType]
+	if omitEmpty {
+		tag += ",omitempty"
+	}
+	return `json:"` + tag + `"`
+}
+
+// xmlTag returns the xml tag of the field using its XML target name, which holds the prefix of the element and
+// the wrapping element of wrapped arrays. Attributes are tagged attr.
+func xmlTag(f Field) string {
+	tag := f.TargetNames[XmlContentType]
+	if f.Xml != nil && f.Xml.Attribute {
+		tag += ",attr"
+	}
+	return `xml:"` + tag + `"`
+}
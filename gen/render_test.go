@@ -0,0 +1,1308 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnumConstNames(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "enums.yaml", `
+components:
+  schemas:
+    Mode:
+      type: string
+      enum: ["", "read write", "read-write", "!"]
+    Level:
+      type: number
+      enum: [-1.5, 1.5, 15, 0]
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), `ModeEmpty Mode = ""`, `ModeReadWrite Mode = "read write"`, `ModeReadWrite2 Mode = "read-write"`,
+		`Mode2 Mode = "!"`, "LevelMinus1_5 Level = -1.5", "Level1_5 Level = 1.5", "Level15 Level = 15", "Level0 Level = 0")
+	compile(t, map[string]string{"models.go": source})
+}
+
+// renderError generates the document and returns the error of rendering it.
+func renderError(t *testing.T, doc string) error {
+	t.Helper()
+	sg := generate(t, NewSchemaGen(), "models.yaml", doc)
+	return sg.Render(&bytes.Buffer{}, "models")
+}
+
+func TestEnumConstClash(t *testing.T) {
+	err := renderError(t, `
+components:
+  schemas:
+    Order:
+      type: object
+      properties:
+        status: {type: string, enum: [open]}
+    OrderStatusOpen:
+      type: string
+`)
+	if err == nil || !strings.Contains(err.Error(), "OrderStatusOpen") || !strings.Contains(err.Error(), XGoName) {
+		t.Fatalf("expected a clash of OrderStatusOpen suggesting %s, got %v", XGoName, err)
+	}
+}
+
+func TestInlineTypesPrefixed(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "models.yaml", `
+components:
+  schemas:
+    Customer:
+      type: object
+      properties:
+        id: {type: string}
+    Order:
+      type: object
+      properties:
+        customer:
+          type: object
+          properties:
+            name: {type: string}
+            address:
+              type: object
+              properties:
+                city: {type: string}
+        lines:
+          type: array
+          items:
+            type: object
+            properties:
+              sku: {type: string}
+    Invoice:
+      type: object
+      properties:
+        customer:
+          type: object
+          properties:
+            vat: {type: string}
+        labels:
+          type: object
+          additionalProperties:
+            type: object
+            properties:
+              text: {type: string}
+`)
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "type Customer struct", "type OrderCustomer struct", "Customer OrderCustomer",
+		"type OrderCustomerAddress struct", "Address OrderCustomerAddress", "type OrderLines struct", "Lines []OrderLines",
+		"type InvoiceCustomer struct", "Customer InvoiceCustomer", "type InvoiceLabels map[string]InvoiceLabelsValue",
+		"type InvoiceLabelsValue struct")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestInlineTypeClash(t *testing.T) {
+	err := renderError(t, `
+components:
+  schemas:
+    OrderCustomer:
+      type: object
+      properties:
+        id: {type: string}
+    Order:
+      type: object
+      properties:
+        customer:
+          type: object
+          properties:
+            name: {type: string}
+`)
+	if err == nil || !strings.Contains(err.Error(), "type OrderCustomer") || !strings.Contains(err.Error(), XGoName) {
+		t.Fatalf("expected a clash of OrderCustomer suggesting %s, got %v", XGoName, err)
+	}
+}
+
+func TestAdditionalPropertiesRoundTrip(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.UnknownFields = true
+	sg = generate(t, sg, "labels.yaml", `
+components:
+  schemas:
+    Labels:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+      additionalProperties:
+        type: integer
+`)
+	source := render(t, sg)
+	if strings.Contains(source, "Extra") {
+		t.Errorf("Extra declared along with AdditionalProperties\n%s", source)
+	}
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var l Labels
+	if err := json.Unmarshal([]byte(` + "`" + `{"name":"a","x":1,"y":2}` + "`" + `), &l); err != nil {
+		panic(err)
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(l.Name, l.AdditionalProperties["x"], l.AdditionalProperties["y"], string(b))
+}
+`,
+	})
+	if want := `a 1 2 {"name":"a","x":1,"y":2}`; strings.TrimSpace(out) != want {
+		t.Errorf("expected %s, got %s", want, out)
+	}
+}
+
+func TestAdditionalPropertiesOfEmbeddedStruct(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Base:
+      type: object
+      properties:
+        id: {type: string}
+      additionalProperties: {type: string}
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/Base"
+        - properties:
+            name: {type: string}
+`)
+	source := render(t, sg)
+	if strings.Contains(source, "UnmarshalJSON") {
+		t.Errorf("JSON methods declared for an embedded struct\n%s", source)
+	}
+	var warned bool
+	for _, w := range sg.Diagnostics.Warnings {
+		warned = warned || w.Field == "Base"
+	}
+	if !warned {
+		t.Errorf("expected a warning for Base, got %v", sg.Diagnostics.Warnings)
+	}
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestNilMapsThroughReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     NilMapMode
+		tags     []string
+		expected string
+	}{
+		{"omit", NilMapOmit, []string{"Tags Tags `json:\"tags,omitempty\"`", "Labels Tags `json:\"labels,omitempty\"`"},
+			"{}\n{\"labels\":{\"a\":\"b\"}}\n"},
+		{"null", NilMapNull, []string{"Tags Tags `json:\"tags\"`", "Labels Tags `json:\"labels\"`"},
+			"{\"labels\":null,\"tags\":null}\n{\"labels\":{\"a\":\"b\"},\"tags\":{}}\n"},
+		{"empty", NilMapEmpty, []string{"Tags Tags `json:\"tags\"`", "Labels Tags `json:\"labels\"`"},
+			"{\"labels\":{},\"tags\":{}}\n{\"labels\":{\"a\":\"b\"},\"tags\":{}}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sg := NewSchemaGen()
+			sg.NilMaps = tt.mode
+			sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Tags:
+      type: object
+      additionalProperties: {type: string}
+    Pet:
+      type: object
+      properties:
+        tags:
+          $ref: "#/components/schemas/Tags"
+        labels:
+          $ref: "#/components/schemas/Tags"
+          nullable: true
+`)
+			source := render(t, sg)
+			assertContains(t, squeeze(source), tt.tags...)
+			out := run(t, map[string]string{"models.go": strings.Replace(source, "package models", "package main", 1),
+				"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	for _, p := range []Pet{{}, {Tags: Tags{}, Labels: Tags{"a": "b"}}} {
+		b, err := json.Marshal(p)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(b))
+	}
+}
+`})
+			if out != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestConstMember(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [version]
+      properties:
+        version: {type: integer, format: int32, minimum: 2, maximum: 2}
+        name: {type: string, example: Rex}
+`)
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "// PetVersion is the only value allowed for Version", "PetVersion int32 = 2", "Version int32")
+	example, err := sg.GoExample("Pet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, example, "Version: 2")
+	compile(t, map[string]string{
+		"models.go":  source,
+		"example.go": "package models\n\nvar _ = " + example + "\n\nvar _ int32 = PetVersion\n",
+	})
+}
+
+func TestPostProcessBuildTag(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.PostProcess = func(b []byte) ([]byte, error) {
+		return append([]byte("//go:build generated\n// +build generated\n\n"), b...), nil
+	}
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+`)
+	source := render(t, sg)
+	if !strings.HasPrefix(source, "//go:build generated\n") {
+		t.Fatalf("build tag not injected\n%s", source)
+	}
+	goCommand(t, map[string]string{"models.go": source}, "vet", "-tags", "generated", ".")
+}
+
+func TestSQLMethods(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.SQLMethods = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        tags:
+          type: array
+          items: {type: string}
+`)
+	source := render(t, sg)
+	assertContains(t, source, "func (v Pet) Value() (driver.Value, error)", "func (v *Pet) Scan(src interface{}) error")
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+var (
+	_ driver.Valuer = Pet{}
+	_ sql.Scanner   = (*Pet)(nil)
+)
+
+func main() {
+	v, err := Pet{Name: "rex", Tags: []string{"a"}}.Value()
+	if err != nil {
+		panic(err)
+	}
+	var fromBytes, fromString, fromNull Pet
+	if err := fromBytes.Scan(v); err != nil {
+		panic(err)
+	}
+	if err := fromString.Scan(string(v.([]byte))); err != nil {
+		panic(err)
+	}
+	if err := fromNull.Scan(nil); err != nil {
+		panic(err)
+	}
+	fmt.Println(string(v.([]byte)), fromBytes.Name, fromString.Tags, fromNull.Name == "", fromNull.Scan(1))
+}
+`,
+	})
+	if want := `{"name":"rex","tags":["a"]} rex [a] true cannot scan int into Pet`; strings.TrimSpace(out) != want {
+		t.Errorf("expected %s, got %s", want, out)
+	}
+}
+
+func TestReservedFieldNames(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.UnknownFields = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        extra: {type: string}
+        petId: {type: string}
+        pet_id: {type: integer}
+    Labels:
+      type: object
+      properties:
+        additionalProperties: {type: string}
+      additionalProperties: {type: integer}
+`)
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "Extra2 *string `json:\"extra,omitempty\"`", "Extra map[string]json.RawMessage",
+		"PetID *string `json:\"petId,omitempty\"`", "PetID2 *int64 `json:\"pet_id,omitempty\"`",
+		"AdditionalProperties2 *string `json:\"additionalProperties,omitempty\"`", "AdditionalProperties map[string]int64")
+	if len(sg.Diagnostics.Warnings) != 3 {
+		t.Errorf("expected the renamed fields to be reported, got %v", sg.Diagnostics.Warnings)
+	}
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestAllOfDiamond(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Entity:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+        kind: {type: string, enum: [pet, owner]}
+    Named:
+      allOf:
+        - $ref: "#/components/schemas/Entity"
+        - properties:
+            name: {type: string}
+    Tagged:
+      allOf:
+        - $ref: "#/components/schemas/Entity"
+        - properties:
+            tag: {type: string}
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/Named"
+        - $ref: "#/components/schemas/Tagged"
+        - properties:
+            age: {type: integer}
+`)
+	source := render(t, sg)
+	pet := source[strings.Index(source, "type Pet struct"):]
+	pet = squeeze(pet[:strings.Index(pet, "}")])
+	assertContains(t, pet, "ID string `json:\"id\"`", "Kind *EntityKind", "Name *string", "Tag *string", "Age *int64")
+	if strings.Contains(pet, "\n\tNamed\n") || strings.Count(pet, "ID ") != 1 {
+		t.Errorf("expected the bases of Pet to be copied once\n%s", pet)
+	}
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	name, tag := "rex", "dog"
+	b, err := json.Marshal(Pet{ID: "1", Name: &name, Tag: &tag})
+	if err != nil {
+		panic(err)
+	}
+	var p Pet
+	if err := json.Unmarshal(b, &p); err != nil {
+		panic(err)
+	}
+	fmt.Println(string(b), p.ID, *p.Name, *p.Tag)
+}
+`,
+	})
+	if want := `{"id":"1","name":"rex","tag":"dog"} 1 rex dog`; strings.TrimSpace(out) != want {
+		t.Errorf("expected %s, got %s", want, out)
+	}
+}
+
+func TestOneOfMergesBranches(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Cat:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        purrs: {type: boolean}
+    Dog:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        barks: {type: boolean}
+    Pet:
+      type: object
+      properties:
+        id: {type: string}
+      oneOf:
+        - $ref: "#/components/schemas/Cat"
+        - $ref: "#/components/schemas/Dog"
+`)
+	source := render(t, sg)
+	pet := source[strings.Index(source, "type Pet struct"):]
+	pet = squeeze(pet[:strings.Index(pet, "}")])
+	assertContains(t, pet, "Barks *bool", "ID *string", "Name *string `json:\"name,omitempty\"`", "Purrs *bool")
+	if strings.Contains(pet, "\nPet ") {
+		t.Errorf("branches declared under the name of the object\n%s", pet)
+	}
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var p Pet
+	if err := json.Unmarshal([]byte(` + "`" + `{"name":"rex","barks":true}` + "`" + `), &p); err != nil {
+		panic(err)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(*p.Name, *p.Barks, p.Purrs == nil, string(b))
+}
+`,
+	})
+	if want := `rex true true {"barks":true,"name":"rex"}`; strings.TrimSpace(out) != want {
+		t.Errorf("expected %s, got %s", want, out)
+	}
+}
+
+func TestEasyJSONMarkers(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.EasyJSON = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      description: A pet of the store
+      properties:
+        name: {type: string}
+        status: {type: string, enum: [available, sold]}
+    Labels:
+      type: object
+      properties:
+        owner: {type: string}
+      additionalProperties: {type: string}
+`)
+	source := render(t, sg)
+	assertContains(t, source, "// A pet of the store\n//\n//easyjson:json\ntype Pet struct")
+	for _, unmarked := range []string{"//easyjson:json\ntype Labels", "//easyjson:json\ntype PetStatus"} {
+		if strings.Contains(source, unmarked) {
+			t.Errorf("unexpected marker %q in\n%s", unmarked, source)
+		}
+	}
+	if len(sg.Diagnostics.Warnings) != 1 || sg.Diagnostics.Warnings[0].Field != "Labels" {
+		t.Errorf("expected a warning for Labels, got %v", sg.Diagnostics.Warnings)
+	}
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestJSONFieldMaps(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.JSONFieldMaps = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Named:
+      type: object
+      properties:
+        name: {type: string}
+        nick_name: {type: string}
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Named'
+        - type: object
+          properties:
+            pet_id: {type: integer}
+            owner:
+              type: object
+              properties:
+                e-mail: {type: string}
+`)
+	source := render(t, sg)
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(PetJSONFields)
+	fmt.Println(PetOwnerJSONFields)
+	fmt.Println(NamedJSONFields)
+}
+`,
+	})
+	expected := "map[name:Name nick_name:NickName owner:Owner pet_id:PetID]\nmap[e-mail:EMail]\nmap[name:Name nick_name:NickName]\n"
+	if out != expected {
+		t.Errorf("expected\n%s\ngot\n%s\n%s", expected, out, source)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.Catalog = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        status: {type: string, enum: [available, sold]}
+        owner:
+          type: object
+          properties:
+            name: {type: string}
+    Tags:
+      type: array
+      items: {type: string}
+`)
+	files := writeDir(t, sg)
+	if _, ok := files[CatalogFile]; !ok {
+		t.Fatalf("expected %s, got %v", CatalogFile, files)
+	}
+	main := make(map[string]string, len(files)+1)
+	for name, content := range files {
+		main[name] = strings.Replace(content, "package models", "package main", 1)
+	}
+	main["main.go"] = `package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+func main() {
+	var names []string
+	for name, typ := range Schemas {
+		names = append(names, name+":"+typ.Kind().String())
+	}
+	sort.Strings(names)
+	fmt.Println(names)
+}
+`
+	if out := run(t, main); out != "[Pet:struct PetOwner:struct PetStatus:string Tags:slice]\n" {
+		t.Errorf("unexpected catalog %s\n%s", out, files[CatalogFile])
+	}
+
+	var buf bytes.Buffer
+	if err := sg.Render(&buf, "models"); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, squeeze(buf.String()), `"PetOwner": reflect.TypeOf((*PetOwner)(nil)).Elem(),`)
+	compile(t, map[string]string{"models.go": buf.String()})
+}
+
+func TestCatalogClash(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.Catalog = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Schemas:
+      type: array
+      items: {type: string}
+`)
+	if err := sg.Render(&bytes.Buffer{}, "models"); err == nil || !strings.Contains(err.Error(), "Schemas") {
+		t.Errorf("expected a clash of Schemas, got %v", err)
+	}
+
+	sg = NewSchemaGen()
+	sg.Catalog = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    catalog:
+      type: string
+`)
+	if err := sg.WriteToDir(t.TempDir(), "models"); err == nil || !strings.Contains(err.Error(), CatalogFile) {
+		t.Errorf("expected a clash with %s, got %v", CatalogFile, err)
+	}
+}
+
+func TestPackageDoc(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.PackageDoc = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        status: {type: string, enum: [available, sold]}
+    Tags:
+      type: array
+      items: {type: string}
+`)
+	files := writeDir(t, sg)
+	expected := `// Package models holds the Go types generated from the schemas of pets.yaml.
+//
+// It declares 3 types.
+package models
+`
+	if files[DocFile] != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, files[DocFile])
+	}
+	compile(t, files)
+
+	sg.GeneratedAt = time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	files = writeDir(t, sg)
+	assertContains(t, files[DocFile], "// It declares 3 types.\n// Generated at 2024-05-01T10:30:00Z.\npackage models\n")
+}
+
+func TestMaxTypesPerFile(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.MaxTypesPerFile = 3
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Category:
+      type: object
+      properties:
+        name: {type: string}
+    Order:
+      type: object
+      properties:
+        status: {type: string, enum: [placed, delivered]}
+        shipDate: {type: string, format: date-time}
+    Pet:
+      type: object
+      properties:
+        status: {type: string, enum: [available, sold]}
+        owner:
+          type: object
+          properties:
+            name: {type: string}
+        size: {type: string, enum: [small, large]}
+    Tag:
+      type: string
+`)
+	files := writeDir(t, sg)
+	declared := map[string][]string{
+		"models_1.go": {"type Category struct", "type Order struct", "type OrderStatus string"},
+		"models_2.go": {"type Pet struct", "type PetOwner struct", "type PetSize string", "type PetStatus string"},
+		"models_3.go": {"type Tag string"},
+	}
+	if len(files) != len(declared) {
+		t.Errorf("expected %d files, got %d", len(declared), len(files))
+	}
+	for file, types := range declared {
+		assertContains(t, files[file], types...)
+	}
+	assertContains(t, files["models_1.go"], `"time"`)
+	compile(t, files)
+}
+
+func TestHeader(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.Header = true
+	sg.PackageDoc = true
+	if err := sg.AddRecord("Row", []Column{{Name: "id", Type: "integer", Required: true}}); err != nil {
+		t.Fatal(err)
+	}
+	sg = generate(t, sg, "pets.yaml", `
+openapi: 3.1.0
+info:
+  title: Pets
+  version: 1.2.0
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+`)
+	generated := regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+	source := render(t, sg)
+	if !strings.HasPrefix(source, "// Code generated by turbo-gen from pets.yaml version 1.2.0. DO NOT EDIT.\n\npackage models\n") {
+		t.Errorf("missing header in\n%s", source)
+	}
+	files := writeDir(t, sg)
+	for file, content := range files {
+		if !generated.MatchString(content) {
+			t.Errorf("%s is not marked as generated\n%s", file, content)
+		}
+	}
+	assertContains(t, files["Pet.go"], "// Code generated by turbo-gen from pets.yaml version 1.2.0. DO NOT EDIT.\n")
+	assertContains(t, files["Row.go"], "// Code generated by turbo-gen. DO NOT EDIT.\n")
+	assertContains(t, files[DocFile], "DO NOT EDIT.\n\n// Package models holds the Go types generated from the schemas of pets.yaml.\n")
+	compile(t, files)
+}
+
+func TestXGoOmitEmpty(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "models.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name, tag]
+      properties:
+        name: {type: string}
+        tag: {type: string, x-go-omitempty: true}
+        age: {type: integer}
+        color: {type: string, x-go-omitempty: false}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Name string `json:\"name\"`", "Tag string `json:\"tag,omitempty\"`",
+		"Age *int64 `json:\"age,omitempty\"`", "Color *string `json:\"color\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestTitleDocComments(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      title: is an animal kept at home.
+      type: object
+      properties:
+        name: {type: string, title: Name the pet answers to.}
+`)
+	assertContains(t, render(t, sg), "// Pet is an animal kept at home.\ntype Pet struct {",
+		"\t// Name the pet answers to.\n\tName *string")
+}
+
+func TestDescriptionDocComments(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      title: is an animal kept at home.
+      description: |-
+        Pets are listed by the store.
+        Sold pets are kept for a year.
+      type: object
+      properties:
+        name: {type: string, description: The name the pet answers to.}
+    Status:
+      type: string
+      description: The availability of a pet.
+      enum: [available, sold]
+`)
+	source := render(t, sg)
+	assertContains(t, source,
+		"// Pet is an animal kept at home.\n//\n// Pets are listed by the store.\n// Sold pets are kept for a year.\ntype Pet struct {",
+		"\t// The name the pet answers to.\n\tName *string", "// The availability of a pet.\ntype Status string")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestEmitComments(t *testing.T) {
+	doc := `
+components:
+  schemas:
+    Pet:
+      $comment: Kept in sync with
+        the store schema.
+      description: A pet.
+      type: object
+      properties:
+        name: {type: string, $comment: Not unique.}
+`
+	sg := NewSchemaGen()
+	sg.EmitComments = true
+	source := render(t, generate(t, sg, "pets.yaml", doc))
+	assertContains(t, source, "// Kept in sync with the store schema.\n\n// A pet.\ntype Pet struct {",
+		"Name *string `json:\"name,omitempty\"` // Not unique.")
+	compile(t, map[string]string{"models.go": source})
+	if source := render(t, generate(t, NewSchemaGen(), "pets.yaml", doc)); strings.Contains(source, "sync") ||
+		strings.Contains(source, "unique") {
+		t.Errorf("expected the comments to be omitted by default\n%s", source)
+	}
+}
+
+func TestCommentWidth(t *testing.T) {
+	doc := `
+components:
+  schemas:
+    Pet:
+      description: A pet is an animal kept at home for company, listed by the store until it is sold to an owner.
+      type: object
+      properties:
+        name: {type: string, description: "The name the pet answers to, which the owner may change."}
+`
+	sg := NewSchemaGen()
+	sg.CommentWidth = 40
+	source := render(t, generate(t, sg, "pets.yaml", doc))
+	assertContains(t, source, "// A pet is an animal kept at home for\n// company, listed by the store until it\n// is sold to an owner.\ntype Pet struct {",
+		"\t// The name the pet answers to, which\n\t// the owner may change.\n")
+	source = render(t, generate(t, NewSchemaGen(), "pets.yaml", doc))
+	assertContains(t, source, "// A pet is an animal kept at home for company, listed by the store until it is\n// sold to an owner.\n")
+	for _, line := range strings.Split(source, "\n") {
+		if comment := strings.TrimLeft(line, "\t"); strings.HasPrefix(comment, "//") && len(comment) > DefaultCommentWidth {
+			t.Errorf("comment line longer than %d: %q", DefaultCommentWidth, comment)
+		}
+	}
+}
+
+func TestUnusualPropertyNames(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "things.yaml", `
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        "@type": {type: string}
+        123abc: {type: string}
+        "a.b": {type: integer}
+    123Thing:
+      type: string
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Type *string `json:\"@type,omitempty\"`", "X123abc *string `json:\"123abc,omitempty\"`",
+		"AB *int64 `json:\"a.b,omitempty\"`", "type X123Thing string")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestRawMessageRoundTrip(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "events.yaml", `
+components:
+  schemas:
+    Event:
+      type: object
+      required: [kind, payload]
+      properties:
+        kind: {type: string}
+        payload:
+          x-go-raw: true
+          type: object
+          properties:
+            id: {type: integer}
+        extra:
+          x-go-raw: true
+          $ref: '#/components/schemas/Kind'
+    Kind: {type: string}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Payload json.RawMessage `json:\"payload\"`",
+		"Extra json.RawMessage `json:\"extra,omitempty\"`")
+	out := run(t, map[string]string{"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	in := ` + "`" + `{"kind":"created","payload":{"id": 1, "tags":["a"]}}` + "`" + `
+	var e Event
+	if err := json.Unmarshal([]byte(in), &e); err != nil {
+		panic(err)
+	}
+	fmt.Println(string(e.Payload))
+	out, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+}
+`})
+	expected := "{\"id\": 1, \"tags\":[\"a\"]}\n{\"kind\":\"created\",\"payload\":{\"id\":1,\"tags\":[\"a\"]}}\n"
+	if out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestXGoEmbedded(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [audit]
+      properties:
+        name: {type: string}
+        audit:
+          $ref: '#/components/schemas/Audit'
+          x-go-embedded: true
+    Audit:
+      type: object
+      properties:
+        created: {type: string}
+`)
+	source := render(t, sg)
+	assertContains(t, source, "type Pet struct {\n\tAudit\n")
+	out := run(t, map[string]string{"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var p Pet
+	if err := json.Unmarshal([]byte(` + "`" + `{"name":"Rex","created":"today"}` + "`" + `), &p); err != nil {
+		panic(err)
+	}
+	fmt.Println(*p.Created)
+}
+`})
+	if out != "today\n" {
+		t.Errorf("expected the embedded field to be promoted, got %q", out)
+	}
+}
+
+func TestDivergentXMLName(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "users.yaml", `
+components:
+  schemas:
+    User:
+      type: object
+      required: [userId]
+      properties:
+        userId:
+          type: string
+          xml: {name: UserIdentifier}
+        email: {type: string}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "UserID string `json:\"userId\" xml:\"UserIdentifier\"`", "Email *string `json:\"email,omitempty\"`\n")
+	out := run(t, map[string]string{"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+)
+
+func main() {
+	u := User{UserID: "u1"}
+	j, _ := json.Marshal(u)
+	x, _ := xml.Marshal(u)
+	fmt.Println(string(j))
+	fmt.Println(string(x))
+}
+`})
+	if expected := "{\"userId\":\"u1\"}\n<User><UserIdentifier>u1</UserIdentifier></User>\n"; out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestXMLTags(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "users.yaml", `
+components:
+  schemas:
+    User:
+      type: object
+      xml: {name: user, namespace: "http://example.com/schema", prefix: ex}
+      properties:
+        id:
+          type: integer
+          xml: {attribute: true}
+        lang:
+          type: string
+          xml: {attribute: true, prefix: ex, name: language}
+        tags:
+          type: array
+          xml: {name: tags, wrapped: true}
+          items:
+            type: string
+            xml: {name: tag}
+        aliases:
+          type: array
+          items: {type: string, xml: {name: alias}}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "ID *int64 `json:\"id,omitempty\" xml:\"id,attr\"`",
+		"Lang *string `json:\"lang,omitempty\" xml:\"ex:language,attr\"`", "Tags []string `json:\"tags,omitempty\" xml:\"tags>tag\"`",
+		"Aliases []string `json:\"aliases,omitempty\" xml:\"alias\"`",
+		"// Declares the namespace http://example.com/schema\nXmlnsEx string `json:\"-\" xml:\"xmlns:ex,attr\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestTimeFields(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "events.yaml", `
+components:
+  schemas:
+    Event:
+      type: object
+      required: [created]
+      properties:
+        created: {type: string, format: date-time}
+        day: {type: string, format: date}
+        ref: {type: string, format: frobnicate}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Created time.Time `json:\"created\"`", "Day *time.Time `json:\"day,omitempty\"`",
+		"Ref *string `json:\"ref,omitempty\"`")
+	out := run(t, map[string]string{"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var e Event
+	if err := json.Unmarshal([]byte(` + "`" + `{"created":"2024-05-06T07:08:09Z"}` + "`" + `), &e); err != nil {
+		panic(err)
+	}
+	fmt.Println(e.Created.Year(), e.Created.Month())
+	b, _ := json.Marshal(e)
+	fmt.Println(string(b))
+}
+`})
+	if expected := "2024 May\n{\"created\":\"2024-05-06T07:08:09Z\"}\n"; out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestByteFields(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "files.yaml", `
+components:
+  schemas:
+    File:
+      type: object
+      required: [content]
+      properties:
+        content: {type: string, format: byte}
+        raw: {type: string, format: binary}
+`)
+	members := sg.SchemaInfos["File"].Fields["File"].(ObjectField).Members
+	if !members["content"].(StringField).Base64 || members["raw"].(StringField).Base64 {
+		t.Errorf("expected only the byte format to be base64 encoded")
+	}
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Content []byte `json:\"content\"`", "Raw []byte `json:\"raw,omitempty\"`")
+	out := run(t, map[string]string{"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var f File
+	if err := json.Unmarshal([]byte(` + "`" + `{"content":"aGVsbG8="}` + "`" + `), &f); err != nil {
+		panic(err)
+	}
+	fmt.Println(string(f.Content))
+}
+`})
+	if out != "hello\n" {
+		t.Errorf("expected the content to be base64 decoded, got %q", out)
+	}
+}
+
+func TestOptionalValues(t *testing.T) {
+	doc := `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        age: {type: integer}
+        vaccinated: {type: boolean}
+        nickname: {type: string, nullable: true}
+`
+	source := squeeze(render(t, generate(t, NewSchemaGen(), "pets.yaml", doc)))
+	assertContains(t, source, "Name string `json:\"name\"`", "Age *int64 `json:\"age,omitempty\"`",
+		"Vaccinated *bool `json:\"vaccinated,omitempty\"`", "Nickname *string `json:\"nickname,omitempty\"`")
+	sg := NewSchemaGen()
+	sg.OptionalValues = true
+	source = render(t, generate(t, sg, "pets.yaml", doc))
+	// Nullable fields keep their pointer to hold null
+	assertContains(t, squeeze(source), "Name string `json:\"name\"`", "Age int64 `json:\"age,omitempty\"`",
+		"Vaccinated bool `json:\"vaccinated,omitempty\"`", "Nickname *string `json:\"nickname,omitempty\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestNullablePointers(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name, nickname, owner, tags]
+      properties:
+        name: {type: string}
+        nickname: {type: string, nullable: true}
+        color: {type: string, nullable: true}
+        owner:
+          type: object
+          nullable: true
+          properties:
+            id: {type: string}
+        tags:
+          type: array
+          nullable: true
+          items: {type: string}
+`)
+	members := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField).Members
+	if !fieldOf(members["nickname"]).Nullable || fieldOf(members["name"]).Nullable {
+		t.Errorf("expected only the nullable fields to be recorded as such")
+	}
+	source := render(t, sg)
+	// Slices hold null without a pointer
+	assertContains(t, squeeze(source), "Name string `json:\"name\"`", "Nickname *string `json:\"nickname\"`",
+		"Color *string `json:\"color,omitempty\"`", "Owner *PetOwner `json:\"owner\"`", "Tags []string `json:\"tags\"`")
+	out := run(t, map[string]string{"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var p Pet
+	if err := json.Unmarshal([]byte(` + "`" + `{"name":"Rex","nickname":null,"owner":null,"tags":null}` + "`" + `), &p); err != nil {
+		panic(err)
+	}
+	b, _ := json.Marshal(p)
+	fmt.Println(p.Nickname == nil, p.Owner == nil, string(b))
+}
+`})
+	if expected := "true true {\"name\":\"Rex\",\"nickname\":null,\"owner\":null,\"tags\":null}\n"; out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestWriteToDir(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    pet_owner:
+      type: object
+      properties:
+        since: {type: string, format: date-time}
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+        owner: {$ref: '#/components/schemas/pet_owner'}
+`)
+	dir := filepath.Join(t.TempDir(), "models")
+	stale := filepath.Join(dir, "Pet.go")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(stale, []byte("package models\n\ntype Stale int\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Created when missing
+	dir = filepath.Join(dir, "nested")
+	files := writeDirAt(t, sg, dir)
+	if len(files) != 2 {
+		t.Fatalf("expected Pet.go and PetOwner.go, got %v", files)
+	}
+	assertContains(t, files["PetOwner.go"], "package models\n", "import (\n\t\"time\"\n)", "type PetOwner struct {")
+	assertContains(t, files["Pet.go"], "package models\n", "type Pet struct {", "Owner PetOwner")
+	if strings.Contains(files["Pet.go"], "import") {
+		t.Errorf("unexpected imports in\n%s", files["Pet.go"])
+	}
+	compile(t, files)
+
+	// Overwritten when present
+	if err := sg.WriteToDir(filepath.Dir(stale), "models"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != files["Pet.go"] {
+		t.Errorf("expected Pet.go to be overwritten, got\n%s", b)
+	}
+}
+
+func TestImportsOfFieldTypes(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "files.yaml", `
+components:
+  schemas:
+    File:
+      type: object
+      properties:
+        created: {type: string, format: date-time}
+        updated: {type: string, format: date-time}
+        content: {type: string, format: byte}
+    Raw:
+      type: object
+      properties:
+        payload: {x-go-raw: true}
+    Plain:
+      type: object
+      properties:
+        name: {type: string}
+`)
+	files := writeDir(t, sg)
+	imports := regexp.MustCompile(`(?s)import \((.*?)\)`)
+	for file, expected := range map[string]string{"File.go": "\n\t\"time\"\n", "Raw.go": "\n\t\"encoding/json\"\n", "Plain.go": ""} {
+		var got string
+		if m := imports.FindStringSubmatch(files[file]); m != nil {
+			got = m[1]
+		}
+		if got != expected {
+			t.Errorf("expected the imports %q in %s, got %q", expected, file, got)
+		}
+	}
+	compile(t, files)
+}
+
+func TestXGoImplements(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      x-go-implements: [fmt.Stringer, encoding/json.Marshaler, Entity]
+      properties:
+        name: {type: string}
+`)
+	source := render(t, sg)
+	assertContains(t, source, "\t\"encoding/json\"\n\t\"fmt\"\n", "var _ fmt.Stringer = (*Pet)(nil)",
+		"var _ json.Marshaler = (*Pet)(nil)", "var _ Entity = (*Pet)(nil)")
+	compile(t, map[string]string{"models.go": source, "entity.go": `package models
+
+type Entity interface {
+	Key() string
+}
+
+func (p *Pet) Key() string { return *p.Name }
+
+func (p *Pet) String() string { return *p.Name }
+
+func (p *Pet) MarshalJSON() ([]byte, error) { return []byte("{}"), nil }
+`})
+}
@@ -3,37 +3,77 @@ package gen
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.nandlabs.io/turbo-gen/spec"
-	"io/ioutil"
+	"go/token"
 	"math"
+	"net/http"
 	"net/url"
+	"path"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
 )
 
 const (
 	Fields          = "fields"
 	IsArray         = "is-array"
 	XmlPrefixes     = "xml-prefixes"
+	XmlWrapper      = "xml-wrapper"
+	AnyOf           = "any-of"
+	AllOf           = "all-of"
 	DocPath         = "doc-path"
 	BasePath        = "base-path"
 	RequiredFields  = "required-fields"
+	Warnings        = "warnings"
+	Visited         = "visited"
+	Names           = "names"
+	Documents       = "documents"
+	EnclosingType   = "enclosing-type"
 	JsonContentType = "application/json"
 	XmlContentType  = "text/xml"
+	XGoName         = "x-go-name"
+	XGoOmitEmpty    = "x-go-omitempty"
+	XGoRaw          = "x-go-raw"
+	XGoEmbedded     = "x-go-embedded"
+	XGoImplements   = "x-go-implements"
 )
 
+// knownExtensions lists the specification extensions understood by the generator.
+var knownExtensions = map[string]bool{
+	XGoName:       true,
+	XGoOmitEmpty:  true,
+	XGoRaw:        true,
+	XGoEmbedded:   true,
+	XGoImplements: true,
+}
+
 type Field struct {
 	Type        string // Type
 	Name        string
 	VarName     string
+	Title       string      // Leading line of the generated doc comment
+	Description string      // Body of the generated doc comment
+	Example     interface{} // Field level example, takes precedence over the examples of the enclosing schema
 	TargetNames map[string]string
 	Required    bool
 	Path        string
 	IsArray     bool
+	OmitEmpty   *bool    // Overrides the required based omitempty decision when set through x-go-omitempty
+	Xml         *XML     // XML representation of the field, nil when the schema does not declare one
+	Comment     string   // $comment of the schema, meant for spec authors rather than for the documentation
+	Nullable    bool     // The value may be null, regardless of the field being required
+	Implements  []string // Interfaces the generated type must implement, set through x-go-implements
 }
 type RefField struct {
 	Field
 	Reference string
+	Default   interface{} // Sibling default of the $ref (OAS 3.1)
+	Pointer   bool        // Set when the reference closes a cycle of schemas or embeds an anyOf branch
+	Embedded  bool        // Set through x-go-embedded to embed the referenced type instead of naming the field
+	TypeName  string      // Go type name of the referenced schema, set once all schemas are generated
 }
 
 type XML struct {
@@ -51,6 +91,7 @@ type StringField struct {
 	MinLen  *int
 	MaxLen  *int
 	Format  *string
+	Base64  bool // Values are base64 encoded (format byte)
 }
 
 type NumberField struct {
@@ -61,6 +102,7 @@ type NumberField struct {
 	MinExclusive *float64
 	MaxExclusive *float64
 	MultipleOf   *float64
+	Const        *float64 // Single allowed value, set when minimum equals maximum
 }
 
 type BooleanField struct {
@@ -72,31 +114,137 @@ type ArrayField struct {
 	Field
 }
 
+// EnumField is a field restricted to a set of values, generated as a named type with one constant per value.
+// The Type of a member enum is prefixed with the name of the enclosing type, as in OrderStatus, and the
+// constants are prefixed with the Type.
+type EnumField struct {
+	Field
+	Values   []interface{}
+	BaseType string // Underlying Go type of the named type
+}
+
+// ObjectField is an object generated as a struct. Its Type is the name of the struct, which is prefixed with the
+// name of the enclosing type for a member object, as in OrderCustomer.
 type ObjectField struct {
 	Field
 	Members              map[string]interface{}
-	AdditionalProperties []interface{}
+	RequiredFields       []string      // Deduplicated required member names in schema order
+	AdditionalProperties []interface{} // Field of the additional property values, empty when they are not allowed
 	MinProperties        int
 	MaxProperties        int
+	AnyOf                bool // Members were merged from anyOf or oneOf branches, none of which is required to match
 }
 
 type SchemaGen struct {
 	SchemaInfos map[string]*SchemaInfo
 	References  map[string]map[string]*SchemaInfo // [docPath]([itemPath]*SchemaInfo)
+	BasePaths   map[string]string                 // [docPath]basePath overrides applied by Add
+	Infos       map[string]spec.Info              // [docPath]Info of the documents the schemas are added from
+	Diagnostics *Diagnostics
+	Logger      Logger `json:"-"`
+	// ResolveBareNames resolves refs such as "Foo" against the registered schema names before loading them as files.
+	ResolveBareNames bool
+	// NarrowIntegers selects the smallest integer type fitting minimum/maximum for integers without a format.
+	NarrowIntegers bool
+	// NilMaps selects how Render encodes nil maps of optional fields.
+	NilMaps NilMapMode
+	// EmitComments renders $comment as plain comments, apart from the doc comments. They are omitted otherwise.
+	EmitComments bool
+	// SQLMethods adds database/sql Scan and Value methods storing the schema objects as JSON.
+	SQLMethods bool
+	// OptionalValues renders optional scalar fields as values instead of pointers, relying on omitempty.
+	OptionalValues bool
+	// Presence renders optional scalar fields as values, as OptionalValues does, and tracks in a bitset of each
+	// struct which of its optional fields were present in the decoded JSON, reported by Has<Field> methods and
+	// set by Set<Field> methods. The optional fields present are encoded even when zero.
+	Presence bool
+	// UnknownFields adds an Extra map to the structs keeping the JSON keys that match none of their fields.
+	UnknownFields bool
+	// EasyJSON marks the structs with //easyjson:json so that easyjson generates their JSON methods, apart from
+	// the structs declaring JSON methods of their own. jsoniter reads the encoding/json tags as is.
+	EasyJSON bool
+	// JSONFieldMaps adds a map of the JSON keys of each struct to the names of its Go fields, the promoted fields
+	// included, named after the struct with a JSONFields suffix.
+	JSONFieldMaps bool
+	// Catalog declares Schemas, the map of the names of all the generated types to their reflect.Type, so that
+	// tools can enumerate them. WriteToDir writes it to catalog.go, Render appends it to the file.
+	Catalog bool
+	// PackageDoc has WriteToDir write a doc.go with the package comment, naming the documents the schemas come
+	// from and the number of types generated.
+	PackageDoc bool
+	// GeneratedAt is the generation time stated by the package comment, left out when zero so that the output
+	// is reproducible.
+	GeneratedAt time.Time
+	// MaxTypesPerFile has WriteToDir pack the schemas, in name order, into files holding at most that many types
+	// and named after the package, as in models_1.go, instead of writing a file per schema. The types of a schema
+	// are kept together, a schema declaring more types gets a file of its own.
+	MaxTypesPerFile int
+	// DomainModels declares along with each struct a domain type named after it with a Model suffix, without
+	// struct tags nor JSON methods, along with the ToWire and FromWire methods converting between both. The
+	// types holding structs get a domain type as well, the other types are shared.
+	DomainModels bool
+	// Header starts the generated files with a comment marking them as generated, not to be edited, and naming
+	// the documents of their schemas along with the info.version of each.
+	Header bool
+	// EqualMethods declares an Equal method on the structs and on the other types not comparable with ==,
+	// comparing the fields deeply without reflection. Free-form values are compared with reflect.DeepEqual.
+	EqualMethods bool
+	// AppendJSON declares on the structs, slices and maps an AppendJSON method appending their JSON encoding to a
+	// buffer without reflection, and a MarshalJSON method calling it. WriteToDir writes the functions they share
+	// to json.go. The types declaring JSON methods of their own are left out, free-form values are still encoded
+	// by encoding/json and raw JSON is appended as it is.
+	AppendJSON bool
+	// TextMethods declares MarshalText and UnmarshalText on the named string, boolean, number and time types, such
+	// as the enums, so that they serve as map keys and query parameters. The enums reject the values they do not list,
+	// when decoding JSON as well. The boolean and number types keep their JSON form through JSON methods of their own.
+	TextMethods bool
+	// KeepExistingTypes has WriteToDir leave out the types already declared by the hand-written Go files of the
+	// target directory, which fail the generation otherwise. The files marked as generated and the tests are not
+	// scanned. The hand-written types must declare the methods the other options rely on.
+	KeepExistingTypes bool
+	// EmbedSchemas declares along with the type of each schema a constant holding the schema as a self-contained
+	// JSON Schema, named after the type with a Schema suffix, and a ValidateJSON method validating JSON documents
+	// against it. The validation is left to ValidateSchema, a function variable of the generated package set to
+	// call the validation library of choice, so that the generated code has no dependencies. WriteToDir writes it
+	// to validate.go.
+	EmbedSchemas bool
+	// FuzzCorpus has WriteToDir write the JSON example of each schema, built from its examples, defaults and
+	// consts, as a seed of the fuzz target named after its type with a Fuzz prefix, under testdata/fuzz. The
+	// fields referencing other schemas are left out of the examples. The fuzz targets are left to write.
+	FuzzCorpus bool
+	// CommentWidth is the width at which Render wraps the descriptions in doc comments, DefaultCommentWidth if not set.
+	CommentWidth int
+	// Names derives the Go identifiers from the schema and property names, DefaultNames if not set.
+	Names NameStrategy `json:"-"`
+	// AllowedHosts lists the hosts from which references using http or https are loaded. References to any other
+	// host fail the generation.
+	AllowedHosts []string
+	// HTTPClient loads the references using http or https, a client with a DefaultFetchTimeout if not set.
+	HTTPClient *http.Client `json:"-"`
+	// Loader loads the referenced documents, reading local files and fetching the remote ones with the HTTPClient
+	// if not set. The hosts of the remote references are checked against the AllowedHosts before loading them.
+	Loader DocumentLoader `json:"-"`
+	// PostProcess transforms the formatted source of each file produced by Render and WriteToDir, for instance to
+	// add a header. Its result is written as is.
+	PostProcess func([]byte) ([]byte, error) `json:"-"`
 }
 
 func NewSchemaGen() SchemaGen {
 	return SchemaGen{SchemaInfos: make(map[string]*SchemaInfo),
-		References: make(map[string]map[string]*SchemaInfo),
+		References:  make(map[string]map[string]*SchemaInfo),
+		BasePaths:   make(map[string]string),
+		Infos:       make(map[string]spec.Info),
+		Diagnostics: &Diagnostics{},
 	}
 }
 
 type SchemaInfo struct {
-	Schema   *spec.Schema
-	Name     string
-	DocPath  *url.URL
-	BasePath *url.URL
-	Fields   map[string]interface{}
+	Schema      *spec.Schema
+	Name        string
+	DocPath     *url.URL
+	BasePath    *url.URL
+	Fields      map[string]interface{}
+	XmlPrefixes map[string]string // [prefix]namespace declared by the XML of the schema and its members
 }
 
 func (sg SchemaGen) Print() {
@@ -107,8 +255,26 @@ func (sg SchemaGen) Print() {
 	}
 }
 
+// SetBasePath registers a canonical base path for all schemas added from docPath.
+// The override replaces the basePath passed to Add so that reference keys are predictable
+// regardless of how each schema was discovered.
+func (sg SchemaGen) SetBasePath(docPath, basePath string) {
+	docUrl, _ := url.Parse(docPath)
+	sg.BasePaths[docUrl.String()] = strings.TrimSuffix(basePath, "/")
+}
+
+// Add registers the schema name of the document at docPath. A schema of the same name declared by another document
+// is not replaced, the clash fails Generate.
 func (sg SchemaGen) Add(name, docPath, basePath string, schema *spec.Schema) {
+	sg.add(name, docPath, basePath, schema)
+}
+
+// add registers the schema like Add and returns the clash with a schema of the same name of another document.
+func (sg SchemaGen) add(name, docPath, basePath string, schema *spec.Schema) error {
 	docUrl, _ := url.Parse(docPath)
+	if override, ok := sg.BasePaths[docUrl.String()]; ok {
+		basePath = override
+	}
 	baseUrl, _ := url.Parse(basePath)
 	itemUrl, _ := url.Parse(basePath + "/" + name)
 	si := &SchemaInfo{
@@ -119,7 +285,14 @@ func (sg SchemaGen) Add(name, docPath, basePath string, schema *spec.Schema) {
 		Fields:   make(map[string]interface{}),
 	}
 
-	sg.SchemaInfos[name] = si
+	sg.tracef("adding schema %s from %s", itemUrl.String(), docUrl.String())
+	var err error
+	if existing, ok := sg.SchemaInfos[name]; ok && existing.DocPath.String() != docUrl.String() {
+		// Only registered for the references, the types generated for both would share the same name
+		err = clashError(name, si, existing)
+	} else {
+		sg.SchemaInfos[name] = si
+	}
 
 	if v, ok := sg.References[docUrl.String()]; ok {
 		v[itemUrl.String()] = si
@@ -128,111 +301,546 @@ func (sg SchemaGen) Add(name, docPath, basePath string, schema *spec.Schema) {
 		ref[itemUrl.String()] = si
 		sg.References[docUrl.String()] = ref
 	}
+	return err
+}
 
+func clashError(name string, si, existing *SchemaInfo) error {
+	return fmt.Errorf("schema %s of document %q clashes with the schema of the same name of document %q", name,
+		si.DocPath.String(), existing.DocPath.String())
 }
 
-func (sg SchemaGen) Generate() {
+// checkClashes returns the clash of the first registered schema, in document order, that is left out of
+// SchemaInfos by a schema of the same name of another document.
+func (sg SchemaGen) checkClashes() error {
+	var docs []string
+	for doc := range sg.References {
+		docs = append(docs, doc)
+	}
+	sort.Strings(docs)
+	for _, doc := range docs {
+		var items []string
+		for item := range sg.References[doc] {
+			items = append(items, item)
+		}
+		sort.Strings(items)
+		for _, item := range items {
+			si := sg.References[doc][item]
+			if existing := sg.SchemaInfos[si.Name]; existing != si {
+				return clashError(si.Name, si, existing)
+			}
+		}
+	}
+	return nil
+}
 
-	for _, si := range sg.SchemaInfos {
-		ctx := context.Background()
+// Generate builds the field model of all the added schemas. All schemas are processed and the first
+// error encountered is returned. The schemas of the documents loaded to resolve references are generated by
+// the same run, each document is loaded once per run.
+func (sg SchemaGen) Generate() error {
+	if err := sg.checkClashes(); err != nil {
+		return err
+	}
+	var firstErr error
+	documents := make(map[string]*spec.OAS)
+	queued := make(map[string]bool)
+	var names []string
+	for i := 0; ; i++ {
+		if i == len(names) {
+			// The schemas of the documents loaded by the previous ones follow them
+			added := pendingNames(sg.SchemaInfos, queued)
+			if len(added) == 0 {
+				break
+			}
+			names = append(names, added...)
+		}
+		si := sg.SchemaInfos[names[i]]
 		xmlPrefixes := make(map[string]string)
-		ctx = context.WithValue(ctx, XmlPrefixes, xmlPrefixes)
-		ctx = context.WithValue(ctx, IsArray, false)
-		ctx = context.WithValue(ctx, Fields, si.Fields)
-		ctx = context.WithValue(ctx, DocPath, si.DocPath)
-		ctx = context.WithValue(ctx, BasePath, si.BasePath)
+		si.XmlPrefixes = xmlPrefixes
+		ctx := withValues(context.Background(),
+			XmlPrefixes, xmlPrefixes,
+			IsArray, false,
+			Fields, si.Fields,
+			DocPath, si.DocPath,
+			BasePath, si.BasePath,
+			Warnings, sg.Diagnostics,
+			Names, sg.names(),
+			Documents, documents)
+
+		visited := map[string]bool{schemaKey(si.DocPath, si.BasePath.String()+"/"+si.Name): true}
+		ctx = withValues(ctx, Visited, visited)
+
+		sg.tracef("generating schema %s", si.Name)
+		if err := sg.handleSchema(si.Name, si.Schema, ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	// References are resolved once all schemas are generated as they may point to schemas generated later
+	for _, name := range names {
+		si := sg.SchemaInfos[name]
+		sg.resolveRefs(si.DocPath, si.Fields)
+	}
+	sg.breakCycles()
+	return firstErr
+}
+
+// breakCycles marks as pointers the references closing a cycle of schemas that hold each other by value, such
+// as A referencing B referencing A, whose structs would otherwise contain themselves. The schemas are walked in
+// name order so that the same reference is marked on every run.
+func (sg SchemaGen) breakCycles() {
+	var names []string
+	for name := range sg.SchemaInfos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	onPath := make(map[*SchemaInfo]bool)
+	done := make(map[*SchemaInfo]bool)
+	for _, name := range names {
+		sg.walkRefs(sg.SchemaInfos[name], onPath, done)
+	}
+}
+
+// walkRefs follows the references si holds by value, depth first, marking those back to a schema on the path.
+func (sg SchemaGen) walkRefs(si *SchemaInfo, onPath, done map[*SchemaInfo]bool) {
+	if done[si] || onPath[si] {
+		return
+	}
+	onPath[si] = true
+	sg.markBackRefs(si, si.Fields, onPath, done)
+	onPath[si] = false
+	done[si] = true
+}
+
+func (sg SchemaGen) markBackRefs(si *SchemaInfo, scope map[string]interface{}, onPath, done map[*SchemaInfo]bool) {
+	var keys []string
+	for k := range scope {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch f := scope[k].(type) {
+		case RefField:
+			// Slices, pointers and nullable references are already rendered as references to the value
+			if f.IsArray || f.Pointer || f.Nullable {
+				continue
+			}
+			target := sg.lookupRef(si.DocPath, f.Reference)
+			if target == nil {
+				continue
+			}
+			if onPath[target] {
+				sg.tracef("reference %s of field %s closes a cycle, using a pointer", f.Reference, k)
+				f.Pointer = true
+				scope[k] = f
+				continue
+			}
+			sg.walkRefs(target, onPath, done)
+		case ObjectField:
+			if !f.IsArray {
+				sg.markBackRefs(si, f.Members, onPath, done)
+			}
+		}
+	}
+}
+
+// pendingNames returns the names of the schemas that are not queued yet, in order, and queues them.
+func pendingNames(schemaInfos map[string]*SchemaInfo, queued map[string]bool) []string {
+	var names []string
+	for name := range schemaInfos {
+		if !queued[name] {
+			queued[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveRefs sets the TypeName of the RefFields of the scope, and of its nested objects, to the type of the
+// schema they reference. References that do not match a registered schema or one of its members are reported.
+func (sg SchemaGen) resolveRefs(docPath *url.URL, scope map[string]interface{}) {
+	for k, v := range scope {
+		switch f := v.(type) {
+		case RefField:
+			if resolved, ok := sg.resolveRef(docPath, f, make(map[string]bool)); ok {
+				scope[k] = resolved
+			} else if f.TypeName == "" && sg.Diagnostics != nil {
+				sg.Diagnostics.Warn(k, "reference %s does not match a registered schema", f.Reference)
+			}
+		case ObjectField:
+			sg.resolveRefs(docPath, f.Members)
+			for i, v := range f.AdditionalProperties {
+				values := map[string]interface{}{k: v}
+				sg.resolveRefs(docPath, values)
+				f.AdditionalProperties[i] = values[k]
+			}
+		}
+	}
+}
+
+// resolveRef returns the reference f with the TypeName of the schema or the member it points to. A reference to
+// a member that is neither an object nor an enum nor a reference is replaced by a copy of the member, as there is
+// no named type for it. It returns false if the reference matches nothing, seen guards against pointer cycles.
+func (sg SchemaGen) resolveRef(docPath *url.URL, f RefField, seen map[string]bool) (interface{}, bool) {
+	if si := sg.lookupRef(docPath, f.Reference); si != nil {
+		f.TypeName = getFieldName(sg.names(), si.Name)
+		if v, ok := si.Fields[si.Name]; ok {
+			f.TypeName = fieldOf(v).Name
+		}
+		return f, true
+	}
+	key := schemaKey(docPath, f.Reference)
+	target, si := sg.lookupMember(docPath, f.Reference)
+	if target == nil || seen[key] {
+		return nil, false
+	}
+	seen[key] = true
+	sg.tracef("resolving reference %s to a member of schema %s", f.Reference, si.Name)
+	if ref, ok := target.(RefField); ok {
+		resolved, ok := sg.resolveRef(si.DocPath, ref, seen)
+		if !ok {
+			return nil, false
+		}
+		target = resolved
+	}
+	switch t := target.(type) {
+	case ObjectField:
+		f.TypeName = t.Type
+	case EnumField:
+		f.TypeName = t.Type
+	case RefField:
+		f.TypeName = t.TypeName
+		if t.Pointer && !t.IsArray {
+			f.TypeName = "*" + f.TypeName
+		}
+	default:
+		m := fieldOf(target)
+		if f.IsArray && m.IsArray {
+			// A slice of slices has no field of its own
+			f.TypeName = "[]" + m.Type
+			return f, true
+		}
+		member := f.Field
+		member.Type = m.Type
+		member.IsArray = f.IsArray || m.IsArray
+		if member.Title == "" && member.Description == "" {
+			member.Title, member.Description = m.Title, m.Description
+		}
+		return withField(target, member), true
+	}
+	if fieldOf(target).IsArray {
+		f.TypeName = "[]" + f.TypeName
+	}
+	return f, true
+}
+
+// lookupRef returns the registered schema at ref relative to docPath, nil if there is none.
+func (sg SchemaGen) lookupRef(docPath *url.URL, ref string) *SchemaInfo {
+	si, tokens := sg.lookupPointer(docPath, ref)
+	if len(tokens) > 0 {
+		return nil
+	}
+	return si
+}
+
+// lookupPointer returns the registered schema whose path is the longest prefix of the JSON pointer of ref relative
+// to docPath, along with the reference tokens of the pointer that follow it. The schema is nil if there is none.
+func (sg SchemaGen) lookupPointer(docPath *url.URL, ref string) (*SchemaInfo, []string) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, nil
+	}
+	// Resolved the same way as the external documents are loaded
+	refUrl := resolveRef(docPath, u)
+	items := sg.References[documentOf(refUrl)]
+	// The paths are registered escaped as URL fragments, with the schema names as is
+	if si, ok := items[(&url.URL{Fragment: refUrl.Fragment}).String()]; ok {
+		return si, nil
+	}
+	tokens := pointerTokens(refUrl.Fragment)
+	for i := len(tokens); i > 0; i-- {
+		if si, ok := items[(&url.URL{Fragment: "/" + strings.Join(tokens[:i], "/")}).String()]; ok {
+			return si, tokens[i:]
+		}
+	}
+	return nil, nil
+}
 
-		sg.handleSchema(si.Name, si.Schema, ctx)
+// lookupMember returns the field generated for the member of a registered schema the JSON pointer of ref relative
+// to docPath points to, along with the schema, nil if there is none. The pointer steps into the properties, the
+// items and the additionalProperties of the schema, following its references.
+func (sg SchemaGen) lookupMember(docPath *url.URL, ref string) (interface{}, *SchemaInfo) {
+	si, tokens := sg.lookupPointer(docPath, ref)
+	if si == nil {
+		return nil, nil
+	}
+	v := si.Fields[si.Name]
+	followed := make(map[*SchemaInfo]bool)
+	for len(tokens) > 0 && v != nil {
+		f := fieldOf(v)
+		if tokens[0] == "items" && f.IsArray {
+			f.IsArray = false
+			v, tokens = withField(v, f), tokens[1:]
+			continue
+		}
+		if r, ok := v.(RefField); ok {
+			if si = sg.lookupRef(si.DocPath, r.Reference); si == nil || followed[si] {
+				return nil, nil
+			}
+			followed[si] = true
+			v = si.Fields[si.Name]
+			continue
+		}
+		o, ok := v.(ObjectField)
+		switch {
+		case !ok || o.IsArray:
+			return nil, nil
+		case tokens[0] == "properties" && len(tokens) > 1:
+			v, tokens = o.Members[tokens[1]], tokens[2:]
+		case tokens[0] == "additionalProperties" && len(o.AdditionalProperties) == 1:
+			v, tokens = o.AdditionalProperties[0], tokens[1:]
+		default:
+			return nil, nil
+		}
+		followed = make(map[*SchemaInfo]bool)
 	}
+	if v == nil {
+		return nil, nil
+	}
+	return v, si
+}
 
+// pointerTokens splits the JSON pointer of a fragment into its reference tokens, unescaping ~1 to / and ~0 to ~
+// as per RFC 6901. The fragment is already percent-decoded by url.Parse.
+func pointerTokens(fragment string) []string {
+	if !strings.HasPrefix(fragment, "/") {
+		return nil
+	}
+	tokens := strings.Split(fragment[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens
 }
 
-func (sg SchemaGen) handleSchema(name string, schema *spec.Schema, ctx context.Context) {
-	if schema.Ref != nil {
+func (sg SchemaGen) handleSchema(name string, schema *spec.Schema, ctx context.Context) error {
+	schema = normalizeType(name, schema, ctx)
+	if nullable := collapseNullable(schema); nullable != nil {
+		sg.tracef("collapsing the composition of %s into a nullable field", name)
+		schema = nullable
+	}
+	if raw, ok := schema.Extension(XGoRaw); ok && raw == true {
+		sg.handleRaw(name, schema, ctx)
+	} else if schema.Ref != nil {
 		f := RefField{}
 		f.Field = getFieldData(name, schema, ctx)
 		f.Type = "ref"
 		f.Reference = *schema.Ref
+		f.Default = schema.Default
+		if embedded, ok := schema.Extension(XGoEmbedded); ok && embedded == true {
+			f.Embedded = true
+		}
+		if base, _ := ctx.Value(AllOf).(bool); base {
+			f.Embedded = true
+			// An anyOf branch may not match, the nil pointer tells it apart
+			f.Pointer, _ = ctx.Value(AnyOf).(bool)
+		}
+		sg.tracef("resolving reference %s for field %s", *schema.Ref, name)
 
 		//Handle Ref here
 		u, err := url.Parse(*schema.Ref)
 		if err != nil {
-			panic("Invalid URI Reference for Field " + name)
+			return &FieldError{Field: name, Err: fmt.Errorf("invalid URI reference %s: %w", *schema.Ref, err)}
 		}
-		if strings.HasPrefix(u.Scheme, "http") {
-			//Get Schema from external source
-			//	Maybe we should not support it as it may be a security issue in SAAS application.
-			// A allow-list of urls  to load schemas would be more secure.
-
-		} else if u.Scheme != "" {
-			panic("Unsupported protocol for reference of Type for Field " + name + " Only http or https are valid")
-		} else {
-			//Either Local Document or relative  Document
-
-			//External  Document
-			if u.Path != "" {
-				//Load External Document relative to current document
-				//The document can be in Yaml or json Format.
-				//TODO Add yaml parser later
-				//TODO add Error Handling
-				currentDocPath := ctx.Value(DocPath).(*url.URL)
-				refUrl, err := currentDocPath.Parse(u.String())
-				if err == nil {
-					f, err := ioutil.ReadFile(refUrl.Path)
-					if err != nil {
-						oas := spec.OAS{}
-						err := json.Unmarshal(f, &oas)
-						if err == nil {
-							for k, v := range oas.Components.Schemas {
-								sg.Add(k, refUrl.Path, refUrl.Fragment, v)
-							}
-						}
-
-					}
+		if sg.isBareName(u) {
+			//Bare name of a registered schema, treat it as a reference to the local component
+			f.Reference = ComponentsBasePath + "/" + u.Path
+		} else if u.Scheme != "" || u.Host != "" || u.Path != "" {
+			//External Document, resolved against the current document so that the relative references of a
+			//remote document stay on its host. The document can be in Yaml or json Format.
+			refUrl := resolveRef(ctx.Value(DocPath).(*url.URL), u)
+			switch refUrl.Scheme {
+			case "http", "https":
+				//Get Schema from external source, only from the allowed hosts as it may be a security issue in SAAS application.
+				if !sg.isAllowedHost(refUrl) {
+					return &FieldError{Field: name, Err: fmt.Errorf("host %s of reference %s is not allowed", refUrl.Host, refUrl.String())}
 				}
-			} else if strings.HasPrefix(u.Fragment, "#") {
-				//Current Document should be handled by the schemagen as it is expected to have all schema
+			case "":
+				//Local document, relative to the working directory unless its path is absolute
+			default:
+				return &FieldError{Field: name, Err: fmt.Errorf("unsupported protocol %s for reference %s, only http or https are valid", refUrl.Scheme, *schema.Ref)}
+			}
+			if err := sg.loadDocument(refUrl, ctx); err != nil {
+				return &FieldError{Field: name, Err: err}
+			}
+		} else if u.Fragment != "" {
+			//Current Document should be handled by the schemagen as it is expected to have all schema
+			//The reference is resolved by Generate once all of them are generated
+		}
+		if visited, ok := ctx.Value(Visited).(map[string]bool); ok && !f.IsArray {
+			if visited[schemaKey(ctx.Value(DocPath).(*url.URL), f.Reference)] {
+				sg.tracef("reference %s of field %s is circular, using a pointer", f.Reference, name)
+				f.Pointer = true
 			}
-
 		}
 		currentScope := ctx.Value(Fields).(map[string]interface{})
 		currentScope[name] = f
 
 	} else {
+		schemaType := resolveType(name, schema, ctx)
+		if schemaType != schema.Type {
+			// The handlers read the type from the schema, which belongs to the loaded document
+			inferred := *schema
+			inferred.Type = schemaType
+			schema = &inferred
+		}
 
-		switch schema.Type {
+		sg.tracef("generating %s field %s", schemaType, name)
+		switch schemaType {
 		case "boolean":
-			sg.handleBoolean(name, schema, ctx)
+			return sg.handleBoolean(name, schema, ctx)
 		case "integer":
-			sg.handleNumeric(name, schema, ctx)
+			return sg.handleNumeric(name, schema, ctx)
 		case "number":
-			sg.handleNumeric(name, schema, ctx)
+			return sg.handleNumeric(name, schema, ctx)
 		case "string":
-			sg.handleString(name, schema, ctx)
+			return sg.handleString(name, schema, ctx)
 		case "array":
-			sg.handleArray(name, schema, ctx)
+			return sg.handleArray(name, schema, ctx)
 		case "object":
-			sg.handleObject(name, schema, ctx)
+			return sg.handleObject(name, schema, ctx)
+		case "":
+			if !isEmptySchema(schema) {
+				warn(ctx, name, "type cannot be inferred from the schema, using interface{}")
+			}
+			sg.handleAny(name, schema, ctx)
+		case "null":
+			warn(ctx, name, "type null holds no value, the field is dropped")
+		default:
+			warn(ctx, name, "unsupported type %s, the field is dropped", schemaType)
+		}
+
+	}
+	return nil
+}
 
+// collapseNullable returns the non null schema of an anyOf or oneOf [schema, {type: null}] marked as nullable,
+// nil if the schema does not follow this pattern. The annotations of the enclosing schema apply to the collapsed
+// schema when it does not declare its own.
+func collapseNullable(schema *spec.Schema) *spec.Schema {
+	branches := schema.AnyOf
+	if branches == nil {
+		branches = schema.OneOf
+	} else if schema.OneOf != nil {
+		return nil
+	}
+	if schema.Type != "" || schema.Properties != nil || len(branches) != 2 {
+		return nil
+	}
+	var other *spec.Schema
+	switch {
+	case strings.EqualFold(branches[0].Type, "null"):
+		other = branches[1]
+	case strings.EqualFold(branches[1].Type, "null"):
+		other = branches[0]
+	default:
+		return nil
+	}
+	collapsed := *other
+	collapsed.Nullable = true
+	if collapsed.Title == "" {
+		collapsed.Title = schema.Title
+	}
+	if collapsed.Description == "" {
+		collapsed.Description = schema.Description
+	}
+	if collapsed.Default == nil {
+		collapsed.Default = schema.Default
+	}
+	if collapsed.Example == nil {
+		collapsed.Example = schema.Example
+	}
+	if collapsed.Xml == nil {
+		collapsed.Xml = schema.Xml
+	}
+	if collapsed.SpecExtension == nil {
+		collapsed.SpecExtension = schema.SpecExtension
+	}
+	return &collapsed
+}
+
+// refName returns the name of the schema at ref, the last segment of its path.
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// schemaKey identifies the schema at ref relative to docPath so that references to the same schema compare equal.
+func schemaKey(docPath *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return resolveRef(docPath, u).String()
+}
+
+// resolveRef resolves the reference u against the document at docPath. The paths of local documents identified
+// by a relative path stay relative to the working directory, where url.ResolveReference would root them.
+func resolveRef(docPath, u *url.URL) *url.URL {
+	resolved := docPath.ResolveReference(u)
+	if resolved.Scheme == "" && resolved.Host == "" && !path.IsAbs(u.Path) && !path.IsAbs(docPath.Path) {
+		resolved.Path = docPath.Path
+		if u.Path != "" {
+			resolved.Path = path.Join(path.Dir(docPath.Path), u.Path)
 		}
+	}
+	return resolved
+}
 
+// isBareName reports whether the reference is the bare name of a registered schema and ResolveBareNames is enabled.
+func (sg SchemaGen) isBareName(u *url.URL) bool {
+	if !sg.ResolveBareNames || u.Path == "" || u.Fragment != "" || strings.ContainsAny(u.Path, "/.") {
+		return false
 	}
+	_, ok := sg.SchemaInfos[u.Path]
+	return ok
 }
 
-func (sg SchemaGen) handleBoolean(name string, schema *spec.Schema, ctx context.Context) {
+func (sg SchemaGen) handleBoolean(name string, schema *spec.Schema, ctx context.Context) error {
 
 	currentScope := ctx.Value(Fields).(map[string]interface{})
 	f := BooleanField{}
 	f.Field = getFieldData(name, schema, ctx)
 	f.Type = "bool"
+	if schema.Format != nil {
+		warn(ctx, name, "format %s is not applicable to type boolean and is ignored", *schema.Format)
+	}
 	if schema.Default != nil {
-		v := schema.Default.(bool)
+		v, ok := schema.Default.(bool)
+		if !ok {
+			return &FieldError{Field: name, Err: fmt.Errorf("default %v is not a boolean", schema.Default)}
+		}
 		f.Default = &v
 	}
 	currentScope[name] = f
+	return nil
 }
 
-func (sg SchemaGen) handleString(name string, schema *spec.Schema, ctx context.Context) {
+// handleRaw keeps the field as json.RawMessage regardless of its schema so that decoding can be deferred.
+func (sg SchemaGen) handleRaw(name string, schema *spec.Schema, ctx context.Context) {
+	currentScope := ctx.Value(Fields).(map[string]interface{})
+	f := getFieldData(name, schema, ctx)
+	f.Type = "json.RawMessage"
+	currentScope[name] = f
+}
+
+// handleAny maps a schema without any type constraint (e.g. {}) to interface{} as it allows any value.
+func (sg SchemaGen) handleAny(name string, schema *spec.Schema, ctx context.Context) {
+	currentScope := ctx.Value(Fields).(map[string]interface{})
+	f := getFieldData(name, schema, ctx)
+	f.Type = "interface{}"
+	currentScope[name] = f
+}
+
+func (sg SchemaGen) handleString(name string, schema *spec.Schema, ctx context.Context) error {
 	currentScope := ctx.Value(Fields).(map[string]interface{})
 	f := StringField{}
 	f.Field = getFieldData(name, schema, ctx)
@@ -248,19 +856,26 @@ func (sg SchemaGen) handleString(name string, schema *spec.Schema, ctx context.C
 		f.MaxLen = schema.MaxLength
 	}
 
-	if schema.Format != nil {
-		f.Format = schema.Format
+	if format := compatibleFormat(name, schema, ctx); format != nil {
+		f.Format = format
+		f.Base64 = *format == "byte"
 	}
 
 	if schema.Default != nil {
-		v := schema.Default.(string)
+		v, ok := schema.Default.(string)
+		if !ok {
+			return &FieldError{Field: name, Err: fmt.Errorf("default %v is not a string", schema.Default)}
+		}
 		f.Default = &v
 	}
+	if schema.Enum != nil {
+		return sg.handleEnum(name, f.Field, f.Type, schema, ctx)
+	}
 	currentScope[name] = f
-
+	return nil
 }
 
-func (sg SchemaGen) handleNumeric(name string, schema *spec.Schema, ctx context.Context) {
+func (sg SchemaGen) handleNumeric(name string, schema *spec.Schema, ctx context.Context) error {
 	currentScope := ctx.Value(Fields).(map[string]interface{})
 	f := NumberField{}
 	f.Field = getFieldData(name, schema, ctx)
@@ -281,71 +896,574 @@ func (sg SchemaGen) handleNumeric(name string, schema *spec.Schema, ctx context.
 		f.MaxExclusive = schema.ExclusiveMaximum
 	}
 
+	if f.Min != nil && f.Max != nil && *f.Min == *f.Max {
+		sg.tracef("field %s only allows %v", name, *f.Min)
+		f.Const = f.Min
+	}
+
 	if schema.MultipleOf != nil {
-		f.MultipleOf = schema.MultipleOf
+		if *schema.MultipleOf > 0 {
+			f.MultipleOf = schema.MultipleOf
+		} else {
+			warn(ctx, name, "multipleOf must be greater than 0, %v is ignored", *schema.MultipleOf)
+		}
 	}
 
 	if schema.Default != nil {
-		v := schema.Default.(float64)
+		v, ok := schema.Default.(float64)
+		if !ok {
+			return &FieldError{Field: name, Err: fmt.Errorf("default %v is not a number", schema.Default)}
+		}
 		f.Default = &v
 	}
 
 	if schema.Type == "integer" {
-		if schema.Format != nil {
-			f.Type = *schema.Format
+		if format := compatibleFormat(name, schema, ctx); format != nil {
+			switch *format {
+			case "int32", "int64":
+				f.Type = *format
+			default:
+				warn(ctx, name, "format %s is not a supported integer format, using int64", *format)
+				f.Type = "int64"
+			}
+		} else if sg.NarrowIntegers {
+			f.Type = narrowIntegerType(schema)
 		} else {
 			f.Type = "int64"
 		}
 
 	} else if schema.Type == "number" {
-		if schema.Maximum != nil && (*schema.Maximum <= math.MaxFloat32) {
+		format := compatibleFormat(name, schema, ctx)
+		if format != nil && *format == "float" {
+			f.Type = "float32"
+		} else if format != nil && *format == "double" {
+			f.Type = "float64"
+		} else if schema.Maximum != nil && (*schema.Maximum <= math.MaxFloat32) {
 			f.Type = "float32"
 		} else {
 			f.Type = "float64"
 		}
 	}
+	if schema.Enum != nil {
+		return sg.handleEnum(name, f.Field, f.Type, schema, ctx)
+	}
+	currentScope[name] = f
+	return nil
+}
+
+// handleEnum stores the field as an EnumField with baseType as its underlying type. The type is named after the
+// field, prefixed with the type enclosing it so that the enums of different objects do not clash.
+// Duplicates are dropped keeping the schema order and a null value makes the field nullable. An enum holding a
+// value that does not match the base type, such as [1, "two"], cannot be a typed enum: the field falls back to
+// interface{}, which is reported.
+func (sg SchemaGen) handleEnum(name string, field Field, baseType string, schema *spec.Schema, ctx context.Context) error {
+	currentScope := ctx.Value(Fields).(map[string]interface{})
+	seen := make(map[interface{}]bool)
+	var values []interface{}
+	for _, v := range schema.Enum {
+		if v == nil {
+			field.Nullable = true
+			continue
+		}
+		var ok bool
+		if baseType == "string" {
+			_, ok = v.(string)
+		} else if n, isNumber := v.(float64); isNumber {
+			ok = strings.HasPrefix(baseType, "float") || n == math.Trunc(n)
+		}
+		if !ok {
+			schemaType := schema.Type
+			if schemaType == "" {
+				schemaType = enumType(schema.Enum)
+			}
+			warn(ctx, name, "enum value %v is not a %s, using interface{}", v, schemaType)
+			field.Type = "interface{}"
+			currentScope[name] = field
+			return nil
+		}
+		if seen[v] {
+			warn(ctx, name, "duplicate enum value %v", v)
+			continue
+		}
+		seen[v] = true
+		values = append(values, v)
+	}
+	f := EnumField{}
+	f.Field = field
+	enclosing, _ := ctx.Value(EnclosingType).(string)
+	f.Type = enclosing + field.Name
+	f.Values = values
+	f.BaseType = baseType
+	sg.tracef("generating enum %s with %d values", f.Type, len(f.Values))
 	currentScope[name] = f
+	return nil
 }
 
-func (sg SchemaGen) handleObject(name string, schema *spec.Schema, ctx context.Context) {
-	//TODO Handle the possible infinite loop
+// narrowIntegerType returns the smallest signed integer type that fits the bounds of the schema.
+// Schemas without both a lower and an upper bound default to int64.
+func narrowIntegerType(schema *spec.Schema) string {
+	lo := schema.Minimum
+	if lo == nil {
+		lo = schema.ExclusiveMinimum
+	}
+	hi := schema.Maximum
+	if hi == nil {
+		hi = schema.ExclusiveMaximum
+	}
+	if lo == nil || hi == nil {
+		return "int64"
+	}
+	switch {
+	case *lo >= math.MinInt8 && *hi <= math.MaxInt8:
+		return "int8"
+	case *lo >= math.MinInt16 && *hi <= math.MaxInt16:
+		return "int16"
+	case *lo >= math.MinInt32 && *hi <= math.MaxInt32:
+		return "int32"
+	}
+	return "int64"
+}
+
+func (sg SchemaGen) handleObject(name string, schema *spec.Schema, ctx context.Context) error {
 	members := make(map[string]interface{})
-	objCtx := context.WithValue(ctx, Fields, members)
 	requiredFields := make(map[string]bool)
+	var required []string
 	if schema.Required != nil {
 		for _, f := range schema.Required {
+			if requiredFields[f] {
+				warn(ctx, name, "duplicate required entry %s", f)
+				continue
+			}
+			requiredFields[f] = true
+			required = append(required, f)
+		}
+	}
+	for _, f := range allOfRequired(schema) {
+		// Merged before any member is generated as a branch may require the members of another one
+		if !requiredFields[f] {
 			requiredFields[f] = true
+			required = append(required, f)
 		}
 	}
-	objCtx = context.WithValue(objCtx, RequiredFields, requiredFields)
+	if optional, _ := ctx.Value(AnyOf).(bool); optional {
+		// Members of an anyOf branch are optional as the branch may not match
+		requiredFields = make(map[string]bool)
+	}
+	enclosing, _ := ctx.Value(EnclosingType).(string)
+	typeName := enclosing + goName(name, schema, ctx)
+	objCtx := withValues(ctx, Fields, members, RequiredFields, requiredFields, IsArray, false, XmlWrapper, "", AnyOf, false, AllOf, false,
+		EnclosingType, typeName)
 	if schema.OneOf != nil {
-		for _, v := range schema.OneOf {
-			sg.handleSchema(name, v, objCtx)
+		sg.tracef("merging %d oneOf schemas into %s", len(schema.OneOf), name)
+		if err := sg.mergeAnyOf(name, schema.OneOf, objCtx); err != nil {
+			return err
 		}
 	}
 
 	if schema.AllOf != nil {
-		for _, v := range schema.AllOf {
-			sg.handleSchema(name, v, objCtx)
+		sg.tracef("merging %d allOf schemas into %s", len(schema.AllOf), name)
+		if err := sg.mergeAllOf(name, schema, objCtx); err != nil {
+			return err
+		}
+	}
+
+	if schema.AnyOf != nil {
+		sg.tracef("merging %d anyOf schemas into %s", len(schema.AnyOf), name)
+		if err := sg.mergeAnyOf(name, schema.AnyOf, objCtx); err != nil {
+			return err
 		}
 	}
 	for k, v := range schema.Properties {
-		sg.handleSchema(k, v, objCtx)
+		if err := sg.handleSchema(k, v, objCtx); err != nil {
+			return err
+		}
+	}
+	// The values are named after the object, with a Value suffix, and prefixed like the object
+	additional, err := sg.handleAdditionalProperties(name, schema, withValues(objCtx, EnclosingType, enclosing))
+	if err != nil {
+		return err
 	}
 
 	currentScope := ctx.Value(Fields).(map[string]interface{})
 	f := ObjectField{}
 	f.Field = getFieldData(name, schema, ctx)
-	f.Type = "struct"
+	f.Type = typeName
 	f.Members = members
+	f.RequiredFields = required
+	f.AnyOf = schema.AnyOf != nil || schema.OneOf != nil
+	f.AdditionalProperties = additional
+	if schema.MinProperties != nil {
+		f.MinProperties = *schema.MinProperties
+	}
+	if schema.MaxProperties != nil {
+		f.MaxProperties = *schema.MaxProperties
+	}
 	currentScope[name] = f
+	return nil
+}
+
+// mergeAllOf merges the allOf schemas into the members of the object. Referenced bases are embedded under
+// their own name so that they share the type generated for the referenced schema. The properties of inline
+// object schemas are merged into the object itself, their required members are collected by allOfRequired.
+// Schemas that only constrain the object, such as {required: [id]}, declare no member.
+func (sg SchemaGen) mergeAllOf(name string, schema *spec.Schema, ctx context.Context) error {
+	allOfCtx := withValues(ctx, AllOf, true)
+	for _, v := range schema.AllOf {
+		switch {
+		case v.Ref != nil:
+			if err := sg.handleSchema(refName(*v.Ref), v, allOfCtx); err != nil {
+				return err
+			}
+		case isInlineObject(v):
+			if err := sg.mergeInline(name, v, ctx); err != nil {
+				return err
+			}
+		case isConstraint(v):
+			sg.tracef("allOf schema of %s only constrains the object", name)
+		default:
+			if err := sg.handleSchema(name, v, allOfCtx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeAnyOf merges the anyOf or oneOf branches into the members of the object the way mergeAllOf does. As none
+// of the branches has to match, their members are optional and the referenced schemas are embedded as pointers.
+func (sg SchemaGen) mergeAnyOf(name string, branches []*spec.Schema, ctx context.Context) error {
+	anyOfCtx := withValues(ctx, AnyOf, true)
+	for _, v := range branches {
+		switch {
+		case v.Ref != nil:
+			if err := sg.handleSchema(refName(*v.Ref), v, withValues(anyOfCtx, AllOf, true)); err != nil {
+				return err
+			}
+		case isInlineObject(v):
+			if err := sg.mergeInline(name, v, anyOfCtx); err != nil {
+				return err
+			}
+		case isConstraint(v):
+			sg.tracef("anyOf schema of %s only constrains the object", name)
+		default:
+			if err := sg.handleSchema(name, v, anyOfCtx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeInline merges the members of an inline object schema of allOf or anyOf into the object.
+func (sg SchemaGen) mergeInline(name string, schema *spec.Schema, ctx context.Context) error {
+	if schema.AllOf != nil {
+		if err := sg.mergeAllOf(name, schema, ctx); err != nil {
+			return err
+		}
+	}
+	if schema.AnyOf != nil {
+		if err := sg.mergeAnyOf(name, schema.AnyOf, ctx); err != nil {
+			return err
+		}
+	}
+	if schema.OneOf != nil {
+		if err := sg.mergeAnyOf(name, schema.OneOf, ctx); err != nil {
+			return err
+		}
+	}
+	for k, p := range schema.Properties {
+		if err := sg.handleSchema(k, p, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isInlineObject reports whether the schema of an allOf or anyOf branch is an object whose members are merged.
+func isInlineObject(schema *spec.Schema) bool {
+	return schema.Type == "object" || schema.Properties != nil || schema.AllOf != nil || schema.AnyOf != nil ||
+		schema.OneOf != nil
+}
+
+// isConstraint reports whether the schema of an allOf or anyOf branch declares no value of its own and only
+// constrains the object, as a schema holding only required does.
+func isConstraint(schema *spec.Schema) bool {
+	return schema.Ref == nil && schema.Type == "" && schema.Items == nil && schema.OneOf == nil && schema.Enum == nil
+}
+
+// allOfRequired returns the required members declared by the inline allOf schemas, nested ones included.
+func allOfRequired(schema *spec.Schema) []string {
+	var required []string
+	for _, v := range schema.AllOf {
+		if v.Ref != nil || !(isInlineObject(v) || isConstraint(v)) {
+			continue
+		}
+		required = append(required, v.Required...)
+		required = append(required, allOfRequired(v)...)
+	}
+	return required
+}
+
+// handleAdditionalProperties returns the field of the additional property values of the object. true allows
+// any value and is kept as interface{}, a schema is generated like a member named after the object with a
+// Value suffix.
+func (sg SchemaGen) handleAdditionalProperties(name string, schema *spec.Schema, ctx context.Context) ([]interface{}, error) {
+	switch v := schema.AdditionalProperties.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if !v {
+			return nil, nil
+		}
+		f := getFieldData(name+"Value", &spec.Schema{}, ctx)
+		f.Type = "interface{}"
+		return []interface{}{f}, nil
+	}
+	b, err := json.Marshal(schema.AdditionalProperties)
+	if err != nil {
+		return nil, &FieldError{Field: name, Err: fmt.Errorf("invalid additionalProperties: %w", err)}
+	}
+	valueSchema := &spec.Schema{}
+	if err := json.Unmarshal(b, valueSchema); err != nil {
+		return nil, &FieldError{Field: name, Err: fmt.Errorf("invalid additionalProperties: %w", err)}
+	}
+	valueName := name + "Value"
+	scope := make(map[string]interface{})
+	valueCtx := withValues(ctx, Fields, scope, RequiredFields, make(map[string]bool))
+	if err := sg.handleSchema(valueName, valueSchema, valueCtx); err != nil {
+		return nil, err
+	}
+	if v, ok := scope[valueName]; ok {
+		return []interface{}{v}, nil
+	}
+	return nil, nil
+}
+
+func (sg SchemaGen) handleArray(name string, schema *spec.Schema, ctx context.Context) error {
+	if schema.Items == nil {
+		return &FieldError{Field: name, Err: errors.New("array without items")}
+	}
+	arrayContext := withValues(ctx, IsArray, true)
+	if schema.Xml != nil && schema.Xml.Wrapped != nil && *schema.Xml.Wrapped {
+		wrapper := name
+		if schema.Xml.Name != nil {
+			wrapper = *schema.Xml.Name
+		}
+		arrayContext = withValues(arrayContext, XmlWrapper, wrapper)
+	}
+	return sg.handleSchema(name, schema.Items, arrayContext)
+
+}
+
+// formatTypes maps the formats defined by the OAS format registry to the schema types they apply to.
+var formatTypes = map[string][]string{
+	"int32":     {"integer", "number"},
+	"int64":     {"integer", "number"},
+	"float":     {"number"},
+	"double":    {"number"},
+	"byte":      {"string"},
+	"binary":    {"string"},
+	"date":      {"string"},
+	"date-time": {"string"},
+	"time":      {"string"},
+	"duration":  {"string"},
+	"password":  {"string"},
+	"email":     {"string"},
+	"uuid":      {"string"},
+	"uri":       {"string"},
+	"hostname":  {"string"},
+	"ipv4":      {"string"},
+	"ipv6":      {"string"},
+}
+
+// compatibleFormat returns the format of the schema if it can be applied to the schema type.
+// A format registered for a different type is reported and ignored. Unregistered formats are returned as is.
+func compatibleFormat(name string, schema *spec.Schema, ctx context.Context) *string {
+	if schema.Format == nil {
+		return nil
+	}
+	types, ok := formatTypes[*schema.Format]
+	if !ok {
+		return schema.Format
+	}
+	for _, t := range types {
+		if t == schema.Type {
+			return schema.Format
+		}
+	}
+	warn(ctx, name, "format %s is not applicable to type %s and is ignored", *schema.Format, schema.Type)
+	return nil
+}
+
+// schemaTypes lists the types defined by JSON Schema.
+var schemaTypes = map[string]bool{
+	"array":   true,
+	"boolean": true,
+	"integer": true,
+	"null":    true,
+	"number":  true,
+	"object":  true,
+	"string":  true,
+}
+
+// normalizeType returns the schema with its type lower cased when it only differs from a JSON Schema type by
+// its casing, such as String or INTEGER. The nonstandard casing is reported. The schema is copied rather than
+// modified as it belongs to the loaded document.
+func normalizeType(name string, schema *spec.Schema, ctx context.Context) *spec.Schema {
+	schemaType := strings.ToLower(schema.Type)
+	if schemaType == schema.Type || !schemaTypes[schemaType] {
+		return schema
+	}
+	warn(ctx, name, "type %s is not lower case, using %s", schema.Type, schemaType)
+	normalized := *schema
+	normalized.Type = schemaType
+	return &normalized
 }
 
-func (sg SchemaGen) handleArray(name string, schema *spec.Schema, ctx context.Context) {
+// resolveType returns the type used to generate the schema, inferring the type of a typeless schema from its
+// keywords. A typeless schema with allOf, or with anyOf or oneOf branches holding an object or a reference, is an
+// object, as is a typeless schema with properties or additionalProperties. A typeless schema with only items is
+// assumed to be an array, which is reported, and a typeless enum takes the type of its values. Object keywords
+// win over items: the items of an object are ignored with a warning. An empty type is returned when the type
+// cannot be inferred.
+func resolveType(name string, schema *spec.Schema, ctx context.Context) string {
+	schemaType := schema.Type
+	if schemaType == "" {
+		switch {
+		case schema.AllOf != nil || hasObjectBranch(schema.AnyOf) || hasObjectBranch(schema.OneOf):
+			return "object"
+		case schema.Properties != nil || schema.AdditionalProperties != nil:
+			schemaType = "object"
+		case schema.Items != nil:
+			warn(ctx, name, "items declared without a type, assuming array")
+			return "array"
+		case schema.Enum != nil:
+			return enumType(schema.Enum)
+		}
+	}
+	if schema.Items != nil && schemaType != "array" {
+		warn(ctx, name, "items ignored for non array type %s", schemaType)
+	}
+	return schemaType
+}
+
+// enumType returns the type of the values of a typeless enum, the type of its first value that is not null.
+// Numbers are integers unless one of them has a fraction. Values of other types are left to handleEnum, which
+// falls back to interface{}. An empty type is returned for objects and arrays.
+func enumType(values []interface{}) string {
+	for _, v := range values {
+		switch v.(type) {
+		case nil:
+			continue
+		case string:
+			return "string"
+		case bool:
+			return "boolean"
+		case float64:
+			for _, v := range values {
+				if n, ok := v.(float64); ok && n != math.Trunc(n) {
+					return "number"
+				}
+			}
+			return "integer"
+		}
+		return ""
+	}
+	return ""
+}
 
-	arrayContext := context.WithValue(ctx, IsArray, true)
-	sg.handleSchema(name, schema.Items, arrayContext)
+// hasObjectBranch reports whether one of the anyOf or oneOf branches is a reference or an object whose members
+// are merged.
+func hasObjectBranch(branches []*spec.Schema) bool {
+	for _, v := range branches {
+		if v.Ref != nil || isInlineObject(v) {
+			return true
+		}
+	}
+	return false
+}
 
+// isEmptySchema reports whether the schema carries no type, composition, properties, additional properties,
+// items or enum, as {} which allows any value.
+func isEmptySchema(schema *spec.Schema) bool {
+	return schema.Type == "" && schema.Items == nil && schema.Properties == nil && schema.AdditionalProperties == nil &&
+		schema.Enum == nil && schema.AllOf == nil && schema.OneOf == nil && schema.AnyOf == nil && schema.Not == nil
+}
+
+// fieldOf returns the common Field data of any of the field types stored in a scope.
+func fieldOf(v interface{}) Field {
+	switch f := v.(type) {
+	case Field:
+		return f
+	case RefField:
+		return f.Field
+	case StringField:
+		return f.Field
+	case NumberField:
+		return f.Field
+	case BooleanField:
+		return f.Field
+	case ArrayField:
+		return f.Field
+	case EnumField:
+		return f.Field
+	case ObjectField:
+		return f.Field
+	}
+	return Field{}
+}
+
+// withField returns the field v with its common Field data replaced by f.
+func withField(v interface{}, f Field) interface{} {
+	switch t := v.(type) {
+	case Field:
+		return f
+	case RefField:
+		t.Field = f
+		return t
+	case StringField:
+		t.Field = f
+		return t
+	case NumberField:
+		t.Field = f
+		return t
+	case BooleanField:
+		t.Field = f
+		return t
+	case ArrayField:
+		t.Field = f
+		return t
+	case EnumField:
+		t.Field = f
+		return t
+	case ObjectField:
+		t.Field = f
+		return t
+	}
+	return v
+}
+
+// goName returns the Go identifier of the field or type generated for the schema, its x-go-name if set.
+func goName(name string, schema *spec.Schema, ctx context.Context) string {
+	names := ctx.Value(Names).(NameStrategy)
+	if v, ok := xGoName(schema, names); ok {
+		return v
+	}
+	return getFieldName(names, name)
+}
+
+// xGoName returns the identifier set through x-go-name, false if the schema does not set one. A name that is not
+// an exported Go identifier is converted by the NameStrategy.
+func xGoName(schema *spec.Schema, names NameStrategy) (string, bool) {
+	v, _ := schema.Extension(XGoName)
+	goName, ok := v.(string)
+	if !ok || goName == "" {
+		return "", false
+	}
+	if !token.IsIdentifier(goName) || !token.IsExported(goName) {
+		goName = getFieldName(names, goName)
+	}
+	return goName, true
 }
 
 func getFieldData(name string, schema *spec.Schema, ctx context.Context) Field {
@@ -360,6 +1478,13 @@ func getFieldData(name string, schema *spec.Schema, ctx context.Context) Field {
 		_, required = requiredFields[name]
 
 	}
+	if optional, _ := ctx.Value(AnyOf).(bool); optional {
+		required = false
+	}
+	if required && schema.ReadOnly {
+		warn(ctx, name, "field is both required and readOnly, requests cannot provide it")
+	}
+	var xml *XML
 	if schema.Xml != nil {
 		if schema.Xml.Name != nil {
 			xmlName := ""
@@ -367,8 +1492,12 @@ func getFieldData(name string, schema *spec.Schema, ctx context.Context) Field {
 				xmlName += *schema.Xml.Prefix + ":"
 			}
 			if schema.Xml.Namespace != nil {
+				prefix := ""
+				if schema.Xml.Prefix != nil {
+					prefix = *schema.Xml.Prefix
+				}
 				xmlPrefixes := ctx.Value(XmlPrefixes).(map[string]string)
-				xmlPrefixes[*schema.Xml.Prefix] = *schema.Xml.Namespace
+				xmlPrefixes[prefix] = *schema.Xml.Namespace
 			}
 
 			xmlName += *schema.Xml.Name
@@ -376,26 +1505,116 @@ func getFieldData(name string, schema *spec.Schema, ctx context.Context) Field {
 		} else {
 			targetNames[XmlContentType] = name
 		}
+		xml = &XML{
+			Name:      targetNames[XmlContentType],
+			Namespace: stringValue(schema.Xml.Namespace),
+			Prefix:    stringValue(schema.Xml.Prefix),
+			Attribute: schema.Xml.Attribute != nil && *schema.Xml.Attribute,
+			Wrapped:   schema.Xml.Wrapped != nil && *schema.Xml.Wrapped,
+		}
+	}
+	if wrapper, ok := ctx.Value(XmlWrapper).(string); ok && wrapper != "" {
+		// Items of a wrapped array are nested in an element named after the array
+		item, ok := targetNames[XmlContentType]
+		if !ok {
+			item = name
+		}
+		targetNames[XmlContentType] = wrapper + ">" + item
+	}
 
+	names := ctx.Value(Names).(NameStrategy)
+	fieldName := getFieldName(names, name)
+	varName := getVarName(names, name)
+	if v, ok := xGoName(schema, names); ok {
+		if raw, _ := schema.Extension(XGoName); raw != v {
+			warn(ctx, name, "%s %v is not an exported Go identifier, using %s", XGoName, raw, v)
+		}
+		fieldName = v
+		varName = v
+	}
+
+	reportUnknownExtensions(name, schema, ctx)
+
+	example := schema.Example
+	if example == nil && len(schema.Examples) > 0 {
+		example = schema.Examples[0]
+	}
+
+	var implements []string
+	if v, ok := schema.Extension(XGoImplements); ok {
+		values, _ := v.([]interface{})
+		for _, value := range values {
+			if s, ok := value.(string); ok && s != "" {
+				implements = append(implements, s)
+			} else {
+				warn(ctx, name, "%s entry %v is not an interface name and is ignored", XGoImplements, value)
+			}
+		}
+		if values == nil {
+			warn(ctx, name, "%s must be a list of interface names", XGoImplements)
+		}
+	}
+
+	var omitEmpty *bool
+	if v, ok := schema.Extension(XGoOmitEmpty); ok {
+		if b, ok := v.(bool); ok {
+			omitEmpty = &b
+		}
 	}
 
 	return Field{
 		Type:        "",
-		Name:        getFieldName(name),
-		VarName:     getVarName(name),
+		Name:        fieldName,
+		VarName:     varName,
+		Title:       schema.Title,
+		Description: schema.Description,
+		Example:     example,
 		TargetNames: targetNames,
 		Required:    required,
 		Path:        "",
 		IsArray:     ctx.Value(IsArray).(bool),
+		OmitEmpty:   omitEmpty,
+		Xml:         xml,
+		Comment:     schema.Comment,
+		Nullable:    schema.Nullable,
+		Implements:  implements,
 	}
 }
 
-func getFieldName(name string) string {
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
 
-	// Add naming formatted according to the final spec.
-	return strings.Title(name)
+// reportUnknownExtensions warns about the x- extensions of the schema that are ignored by the generator.
+func reportUnknownExtensions(name string, schema *spec.Schema, ctx context.Context) {
+	var unknown []string
+	for k := range schema.SpecExtension {
+		if !knownExtensions[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		warn(ctx, name, "unhandled extension %s", k)
+	}
 }
 
-func getVarName(name string) string {
-	return strings.Title(name)
+// toIdentifier strips the runes that are not valid in a Go identifier and prefixes names that
+// would otherwise start with a digit (or be empty) with X so that the identifier stays exported.
+// The original name is retained in the TargetNames of the field.
+func toIdentifier(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			sb.WriteRune(r)
+		}
+	}
+	id := sb.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "X" + id
+	}
+	return id
 }
@@ -0,0 +1,843 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.nandlabs.io/turbo-gen/spec"
+)
+
+func TestCrossReferenceCycle(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "cycle.yaml", `
+components:
+  schemas:
+    Author:
+      type: object
+      required: [latest]
+      properties:
+        latest:
+          $ref: "#/components/schemas/Book"
+    Book:
+      type: object
+      required: [author]
+      properties:
+        author:
+          $ref: "#/components/schemas/Author"
+`)
+	source := render(t, sg)
+	assertContains(t, source, "Latest Book", "Author *Author")
+	if strings.Contains(source, "Latest *Book") {
+		t.Errorf("both references of the cycle use a pointer\n%s", source)
+	}
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestAnyOfMergesBranches(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Cat:
+      type: object
+      properties:
+        purrs: {type: boolean}
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+      required: [name]
+      anyOf:
+        - $ref: "#/components/schemas/Cat"
+        - type: object
+          required: [barks]
+          properties:
+            barks: {type: boolean}
+`)
+	pet := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+	if !pet.AnyOf {
+		t.Error("Pet not marked as merged from anyOf")
+	}
+	if f := pet.Members["Cat"].(RefField); !f.Embedded || !f.Pointer {
+		t.Errorf("expected Cat to be embedded as a pointer, got %+v", f)
+	}
+	if fieldOf(pet.Members["barks"]).Required {
+		t.Error("member of an anyOf branch is required")
+	}
+	source := render(t, sg)
+	assertContains(t, source, "\t*Cat\n", "Barks *bool", "Name  string")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestAllOfMerge(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Base:
+      type: object
+      properties:
+        id: {type: string}
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/Base"
+        - properties:
+            name: {type: string}
+        - required: [name, tag]
+        - type: object
+          properties:
+            tag: {type: string}
+`)
+	pet, ok := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+	if !ok {
+		t.Fatalf("typeless allOf schema not generated as an object: %#v", sg.SchemaInfos["Pet"].Fields)
+	}
+	if len(pet.Members) != 3 {
+		t.Errorf("expected the members Base, name and tag, got %v", pet.Members)
+	}
+	if strings.Join(pet.RequiredFields, ",") != "name,tag" {
+		t.Errorf("expected name and tag to be required, got %v", pet.RequiredFields)
+	}
+	for _, k := range []string{"name", "tag"} {
+		if !fieldOf(pet.Members[k]).Required {
+			t.Errorf("member %s is not required", k)
+		}
+	}
+	source := render(t, sg)
+	assertContains(t, source, "\tBase\n", "Name string", "Tag  string")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestEnumNamedAfterEnclosingType(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "orders.yaml", `
+components:
+  schemas:
+    Status:
+      type: string
+      enum: [draft, final]
+    Order:
+      type: object
+      properties:
+        status: {type: string, enum: [open, closed]}
+    Invoice:
+      type: object
+      properties:
+        status: {type: string, enum: [paid, due]}
+`)
+	source := render(t, sg)
+	assertContains(t, source, "type Status string", "type OrderStatus string", "OrderStatusOpen", "type InvoiceStatus string",
+		"InvoiceStatusPaid", "Status *OrderStatus", "Status *InvoiceStatus")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestInvalidXGoName(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        owner_id:
+          type: string
+          x-go-name: owner-id
+        tag:
+          type: string
+          x-go-name: Label
+`)
+	if len(sg.Diagnostics.Warnings) != 1 || sg.Diagnostics.Warnings[0].Field != "owner_id" {
+		t.Errorf("expected a warning for the x-go-name of owner_id, got %v", sg.Diagnostics.Warnings)
+	}
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "OwnerID *string", "Label *string")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestTypeXGoName(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    pet_record:
+      type: object
+      x-go-name: Animal
+      properties:
+        owner:
+          type: object
+          x-go-name: Keeper
+          properties:
+            name:
+              type: string
+        status:
+          type: string
+          x-go-name: State
+          enum: [available, sold]
+    Shelter:
+      type: object
+      properties:
+        pets:
+          type: array
+          items:
+            $ref: '#/components/schemas/pet_record'
+`)
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "type Animal struct", "type AnimalKeeper struct", "Keeper AnimalKeeper",
+		"type AnimalState string", "AnimalStateAvailable AnimalState", "State *AnimalState", "Pets []Animal")
+	if strings.Contains(source, "PetRecord") {
+		t.Errorf("expected x-go-name to replace the schema name in\n%s", source)
+	}
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestCollapseNullable(t *testing.T) {
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		t.Run(keyword, func(t *testing.T) {
+			sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Owner:
+      type: object
+      properties:
+        name: {type: string}
+    Pet:
+      type: object
+      required: [nickname, owner]
+      properties:
+        nickname:
+          description: Name the pet answers to
+          `+keyword+`:
+            - type: string
+            - type: "null"
+        owner:
+          `+keyword+`:
+            - type: "null"
+            - $ref: "#/components/schemas/Owner"
+`)
+			pet := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+			nickname, ok := pet.Members["nickname"].(StringField)
+			if !ok || !nickname.Nullable || nickname.Description != "Name the pet answers to" {
+				t.Errorf("expected a nullable string keeping the description, got %#v", pet.Members["nickname"])
+			}
+			if owner, ok := pet.Members["owner"].(RefField); !ok || !owner.Nullable {
+				t.Errorf("expected a nullable reference, got %#v", pet.Members["owner"])
+			}
+			source := squeeze(render(t, sg))
+			assertContains(t, source, "Nickname *string `json:\"nickname\"`", "Owner *Owner `json:\"owner\"`")
+			compile(t, map[string]string{"models.go": source})
+		})
+	}
+}
+
+func TestTypeCasingAndList(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: Object
+      required: [name, nickname, age]
+      properties:
+        name: {type: String}
+        nickname: {type: [string, "null"]}
+        age: {type: ["null", INTEGER]}
+`)
+	pet, ok := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+	if !ok {
+		t.Fatalf("type Object not generated as an object: %#v", sg.SchemaInfos["Pet"].Fields)
+	}
+	if f, ok := pet.Members["nickname"].(StringField); !ok || !f.Nullable {
+		t.Errorf("expected a nullable string, got %#v", pet.Members["nickname"])
+	}
+	if len(sg.Diagnostics.Warnings) != 3 {
+		t.Errorf("expected the casing of Object, String and INTEGER to be reported, got %v", sg.Diagnostics.Warnings)
+	}
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "Name string `json:\"name\"`", "Nickname *string `json:\"nickname\"`", "Age *int64 `json:\"age\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestNumericFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		goType string
+		warns  bool
+	}{
+		{"int32", "{type: integer, format: int32}", "int32", false},
+		{"int64", "{type: integer, format: int64}", "int64", false},
+		{"integer without format", "{type: integer}", "int64", false},
+		{"unknown integer format", "{type: integer, format: uint64}", "int64", true},
+		{"float on integer", "{type: integer, format: float}", "int64", true},
+		{"float", "{type: number, format: float}", "float32", false},
+		{"double", "{type: number, format: double}", "float64", false},
+		{"number without format", "{type: number}", "float64", false},
+		{"number within float32", "{type: number, maximum: 100}", "float32", false},
+		{"number beyond float32", "{type: number, maximum: 1e39}", "float64", false},
+		{"unknown number format", "{type: number, format: decimal}", "float64", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        weight: `+tt.schema+`
+`)
+			pet := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+			f, ok := pet.Members["weight"].(NumberField)
+			if !ok || f.Type != tt.goType {
+				t.Errorf("expected a %s, got %#v", tt.goType, pet.Members["weight"])
+			}
+			if warned := len(sg.Diagnostics.Warnings) > 0; warned != tt.warns {
+				t.Errorf("expected a warning %t, got %v", tt.warns, sg.Diagnostics.Warnings)
+			}
+		})
+	}
+}
+
+func TestTypelessAnyOfAndOneOf(t *testing.T) {
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		t.Run(keyword, func(t *testing.T) {
+			sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Cat:
+      type: object
+      properties:
+        purrs: {type: boolean}
+    Pet:
+      `+keyword+`:
+        - $ref: "#/components/schemas/Cat"
+        - properties:
+            barks: {type: boolean}
+`)
+			pet, ok := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+			if !ok || !pet.AnyOf {
+				t.Fatalf("typeless %s schema not generated as an object: %#v", keyword, sg.SchemaInfos["Pet"].Fields)
+			}
+			source := squeeze(render(t, sg))
+			assertContains(t, source, "type Pet struct", "*Cat", "Barks *bool")
+			compile(t, map[string]string{"models.go": source})
+		})
+	}
+}
+
+func TestMixedEnumFallsBackToInterface(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        size: {type: string, enum: [small, 2, true]}
+        legs: {type: integer, enum: [2, 4.5]}
+        color: {type: string, enum: [black, white, null]}
+`)
+	pet := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+	for _, k := range []string{"size", "legs"} {
+		if f, ok := pet.Members[k].(Field); !ok || f.Type != "interface{}" {
+			t.Errorf("expected %s to fall back to interface{}, got %#v", k, pet.Members[k])
+		}
+	}
+	if f, ok := pet.Members["color"].(EnumField); !ok || !f.Nullable || len(f.Values) != 2 {
+		t.Errorf("expected a nullable enum of two values, got %#v", pet.Members["color"])
+	}
+	if len(sg.Diagnostics.Warnings) != 2 || !strings.Contains(sg.Diagnostics.Warnings[0].Message, "interface{}") {
+		t.Errorf("expected a warning for each fallback, got %v", sg.Diagnostics.Warnings)
+	}
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "Size interface{}", "Legs interface{}", "Color *PetColor")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestTypelessSchemas(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		goType string // Type of the field in the struct, empty when the field is dropped
+		warns  int
+	}{
+		{"properties", "{properties: {city: {type: string}}}", "PetMember", 0},
+		{"additionalProperties", "{additionalProperties: {type: integer}}", "PetMember", 0},
+		{"properties and items", "{properties: {city: {type: string}}, items: {type: string}}", "PetMember", 1},
+		{"items", "{items: {type: string}}", "[]string", 1},
+		{"string enum", "{enum: [a, b]}", "*PetMember", 0},
+		{"integer enum", "{enum: [1, 2]}", "*PetMember", 0},
+		{"number enum", "{enum: [1, 2.5]}", "*PetMember", 0},
+		{"boolean enum", "{enum: [true]}", "*bool", 0},
+		{"mixed enum", "{enum: [1, two]}", "interface{}", 1},
+		{"not", "{not: {type: string}}", "interface{}", 1},
+		{"scalar oneOf", "{oneOf: [{type: string}, {type: integer}]}", "interface{}", 1},
+		{"null", "{type: \"null\"}", "", 1},
+		{"unsupported type", "{type: file}", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id: {type: string}
+        member: `+tt.schema+`
+`)
+			if len(sg.Diagnostics.Warnings) != tt.warns {
+				t.Errorf("expected %d warnings, got %v", tt.warns, sg.Diagnostics.Warnings)
+			}
+			source := squeeze(render(t, sg))
+			if tt.goType == "" {
+				if strings.Contains(source, "Member") {
+					t.Errorf("expected the member to be dropped\n%s", source)
+				}
+			} else {
+				assertContains(t, source, "Member "+tt.goType+" `")
+			}
+			compile(t, map[string]string{"models.go": source})
+		})
+	}
+}
+
+func TestEmptySchema(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [data]
+      properties:
+        data: {}
+        notes: {description: Free form notes}
+`)
+	pet := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField)
+	for _, k := range []string{"data", "notes"} {
+		if f, ok := pet.Members[k].(Field); !ok || f.Type != "interface{}" {
+			t.Errorf("expected %s to be interface{}, got %#v", k, pet.Members[k])
+		}
+	}
+	if len(sg.Diagnostics.Warnings) > 0 {
+		t.Errorf("unexpected warnings %v", sg.Diagnostics.Warnings)
+	}
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "Data interface{} `json:\"data\"`", "Notes interface{} `json:\"notes,omitempty\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestIsEmptySchema(t *testing.T) {
+	values := []interface{}{"a"}
+	tests := []struct {
+		name   string
+		schema *spec.Schema
+		empty  bool
+	}{
+		{"empty", &spec.Schema{}, true},
+		{"annotated", &spec.Schema{Title: "Any", Description: "Anything"}, true},
+		{"additionalProperties", &spec.Schema{AdditionalProperties: true}, false},
+		{"enum", &spec.Schema{Enum: values}, false},
+		{"items", &spec.Schema{Items: &spec.Schema{}}, false},
+		{"properties", &spec.Schema{Properties: map[string]*spec.Schema{}}, false},
+		{"not", &spec.Schema{Not: &spec.Schema{}}, false},
+	}
+	for _, tt := range tests {
+		if got := isEmptySchema(tt.schema); got != tt.empty {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.empty, got)
+		}
+	}
+}
+
+func TestPointerTokens(t *testing.T) {
+	tests := map[string][]string{
+		"":                           nil,
+		"Pet":                        nil,
+		"/components/schemas/Pet":    {"components", "schemas", "Pet"},
+		"/a~1b/c~0d/~01":             {"a/b", "c~d", "~1"},
+		"/components/schemas/Pet ID": {"components", "schemas", "Pet ID"},
+	}
+	for fragment, expected := range tests {
+		if got := pointerTokens(fragment); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected %q, got %q", fragment, expected, got)
+		}
+	}
+}
+
+func TestEscapedPointerRefs(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet Record:
+      type: object
+      properties:
+        owner/contact:
+          type: object
+          properties:
+            email:
+              type: string
+        rating~score:
+          type: integer
+          format: int32
+          description: Rating out of ten
+        labels:
+          type: object
+          additionalProperties:
+            type: string
+            enum: [new, old]
+    Shelter:
+      type: object
+      required: [pet]
+      properties:
+        pet:
+          $ref: '#/components/schemas/Pet%20Record'
+        contact:
+          $ref: '#/components/schemas/Pet%20Record/properties/owner~1contact'
+        score:
+          $ref: '#/components/schemas/Pet%20Record/properties/rating~0score'
+        label:
+          $ref: '#/components/schemas/Pet%20Record/properties/labels/additionalProperties'
+`)
+	if len(sg.Diagnostics.Warnings) > 0 {
+		t.Errorf("unexpected warnings %v", sg.Diagnostics.Warnings)
+	}
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "Pet PetRecord `json:\"pet\"`", "Contact PetRecordOwnerContact `json:\"contact,omitempty\"`",
+		"// Rating out of ten\nScore *int32 `json:\"score,omitempty\"`", "Label PetRecordLabelsValue `json:\"label,omitempty\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestItemsPointerRefs(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    List:
+      type: array
+      items:
+        $ref: '#/components/schemas/Pet'
+    Tags:
+      type: array
+      items:
+        type: string
+        format: date-time
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+    Shelter:
+      type: object
+      properties:
+        pets:
+          type: array
+          items:
+            $ref: '#/components/schemas/Pet'
+    Summary:
+      type: object
+      required: [first, tag, pet, all]
+      properties:
+        first:
+          $ref: '#/components/schemas/List/items'
+        tag:
+          $ref: '#/components/schemas/Tags/items'
+        pet:
+          $ref: '#/components/schemas/Shelter/properties/pets/items'
+        all:
+          $ref: '#/components/schemas/Shelter/properties/pets'
+`)
+	if len(sg.Diagnostics.Warnings) > 0 {
+		t.Errorf("unexpected warnings %v", sg.Diagnostics.Warnings)
+	}
+	source := squeeze(render(t, sg))
+	assertContains(t, source, "type List []Pet", "First Pet `json:\"first\"`", "Tag time.Time `json:\"tag\"`",
+		"Pet Pet `json:\"pet\"`", "All []Pet `json:\"all\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestSetBasePath(t *testing.T) {
+	sg := NewSchemaGen()
+	docPath := "schemas.json"
+	sg.SetBasePath(docPath, "#/$defs/")
+	ownerRef := "#/$defs/Owner"
+	// The base path passed to Add is replaced by the one set for the document
+	sg.Add("Pet", docPath, ComponentsBasePath, &spec.Schema{Type: "object", Properties: map[string]*spec.Schema{
+		"owner": {Reference: spec.Reference{Ref: &ownerRef}},
+	}})
+	sg.Add("Owner", docPath, ComponentsBasePath, &spec.Schema{Type: "object", Properties: map[string]*spec.Schema{
+		"name": {Type: "string"},
+	}})
+	if err := sg.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sg.References[docPath]["#/$defs/Owner"]; !ok {
+		t.Fatalf("Owner not registered under the base path, got %v", sg.References[docPath])
+	}
+	if len(sg.Diagnostics.Warnings) > 0 {
+		t.Errorf("unexpected warnings %v", sg.Diagnostics.Warnings)
+	}
+	assertContains(t, squeeze(render(t, sg)), "Owner Owner `json:\"owner,omitempty\"`")
+}
+
+func TestUnknownExtensionWarning(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string, x-foo: bar, x-go-name: Title}
+`)
+	assertWarning(t, sg, "name", "unhandled extension x-foo")
+	if len(sg.Diagnostics.Warnings) != 1 {
+		t.Errorf("expected x-go-name to be known, got %v", sg.Diagnostics.Warnings)
+	}
+}
+
+func TestFormatMismatchWarning(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        born: {type: integer, format: date-time}
+`)
+	assertWarning(t, sg, "born", "format date-time is not applicable to type integer")
+	assertContains(t, squeeze(render(t, sg)), "Born *int64 `json:\"born,omitempty\"`")
+}
+
+func TestMultipleOfZeroWarning(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        legs: {type: integer, multipleOf: 0}
+        weight: {type: number, multipleOf: 0.5}
+`)
+	assertWarning(t, sg, "legs", "multipleOf must be greater than 0, 0 is ignored")
+	members := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField).Members
+	if legs := members["legs"].(NumberField); legs.MultipleOf != nil {
+		t.Errorf("expected multipleOf 0 to be dropped, got %v", *legs.MultipleOf)
+	}
+	if weight := members["weight"].(NumberField); weight.MultipleOf == nil || *weight.MultipleOf != 0.5 {
+		t.Errorf("expected multipleOf 0.5 to be kept, got %v", weight.MultipleOf)
+	}
+}
+
+func TestBooleanFormatWarning(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        vaccinated: {type: boolean, format: yes-no}
+`)
+	assertWarning(t, sg, "vaccinated", "format yes-no is not applicable to type boolean")
+	assertContains(t, squeeze(render(t, sg)), "Vaccinated *bool `json:\"vaccinated,omitempty\"`")
+}
+
+func TestDuplicateRequired(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name, name, tag]
+      properties:
+        name: {type: string}
+        tag: {type: string}
+`)
+	assertWarning(t, sg, "Pet", "duplicate required entry name")
+	if len(sg.Diagnostics.Warnings) != 1 {
+		t.Errorf("expected a single warning, got %v", sg.Diagnostics.Warnings)
+	}
+	if required := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField).RequiredFields; !reflect.DeepEqual(required, []string{"name", "tag"}) {
+		t.Errorf("expected the required fields [name tag], got %v", required)
+	}
+	assertContains(t, squeeze(render(t, sg)), "Name string `json:\"name\"`", "Tag string `json:\"tag\"`")
+}
+
+func TestResolveBareNames(t *testing.T) {
+	doc := `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [owner]
+      properties:
+        owner: {$ref: Owner}
+    Owner:
+      type: object
+      properties:
+        name: {type: string}
+`
+	sg := NewSchemaGen()
+	sg.ResolveBareNames = true
+	sg = generate(t, sg, filepath.Join(t.TempDir(), "pets.yaml"), doc)
+	assertContains(t, squeeze(render(t, sg)), "Owner Owner `json:\"owner\"`")
+
+	// Loaded as a file otherwise
+	sg = NewSchemaGen()
+	if err := sg.AddFromReader(strings.NewReader(doc), filepath.Join(t.TempDir(), "pets.yaml"), FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.Generate(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the bare name to be loaded as a missing file, got %v", err)
+	}
+}
+
+func TestRefSiblingDefault(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+openapi: 3.1.0
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        status:
+          $ref: '#/components/schemas/Status'
+          default: available
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Status: {type: string, enum: [available, sold]}
+    Owner:
+      type: object
+      properties:
+        name: {type: string}
+`)
+	members := sg.SchemaInfos["Pet"].Fields["Pet"].(ObjectField).Members
+	if status := members["status"].(RefField); status.Default != "available" {
+		t.Errorf("expected the default available, got %v", status.Default)
+	}
+	if owner := members["owner"].(RefField); owner.Default != nil {
+		t.Errorf("expected no default, got %v", owner.Default)
+	}
+}
+
+func TestNarrowIntegers(t *testing.T) {
+	tests := []struct {
+		bounds   string
+		expected string
+	}{
+		{"minimum: 0, maximum: 100", "int8"},
+		{"minimum: -128, maximum: 127", "int8"},
+		{"minimum: 0, maximum: 128", "int16"},
+		{"minimum: -40000, maximum: 0", "int32"},
+		{"exclusiveMinimum: 0, exclusiveMaximum: 70000", "int32"},
+		{"minimum: 0, maximum: 3000000000", "int64"},
+		{"minimum: 0", "int64"},
+		{"maximum: 10", "int64"},
+		{"minimum: 0, maximum: 10, format: int64", "int64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bounds, func(t *testing.T) {
+			sg := NewSchemaGen()
+			sg.NarrowIntegers = true
+			sg = generate(t, sg, "counts.yaml", `
+components:
+  schemas:
+    Count: {type: integer, `+tt.bounds+`}
+`)
+			if typ := sg.SchemaInfos["Count"].Fields["Count"].(NumberField).Type; typ != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, typ)
+			}
+		})
+	}
+}
+
+func TestSelfReference(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "nodes.yaml", `
+components:
+  schemas:
+    Node:
+      type: object
+      required: [value, next]
+      properties:
+        value: {type: string}
+        next: {$ref: '#/components/schemas/Node'}
+        children:
+          type: array
+          items: {$ref: '#/components/schemas/Node'}
+`)
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Next *Node `json:\"next\"`", "Children []Node `json:\"children,omitempty\"`")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestDuplicateEnumValues(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Status: {type: string, enum: [available, sold, available]}
+`)
+	assertWarning(t, sg, "Status", "duplicate enum value available")
+	if values := sg.SchemaInfos["Status"].Fields["Status"].(EnumField).Values; !reflect.DeepEqual(values, []interface{}{"available", "sold"}) {
+		t.Errorf("expected the values [available sold], got %v", values)
+	}
+	source := render(t, sg)
+	if strings.Count(source, `"available"`) != 1 {
+		t.Errorf("expected a single constant for available\n%s", source)
+	}
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestLocalReference(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "shop.yaml", `
+components:
+  schemas:
+    Customer:
+      type: object
+      required: [address]
+      properties:
+        address: {$ref: '#/components/schemas/Address'}
+    Address:
+      type: object
+      properties:
+        city: {type: string}
+`)
+	address := sg.SchemaInfos["Customer"].Fields["Customer"].(ObjectField).Members["address"].(RefField)
+	if address.Reference != "#/components/schemas/Address" || address.TypeName != "Address" {
+		t.Errorf("expected the reference to resolve to Address, got %s resolved to %q", address.Reference, address.TypeName)
+	}
+	source := render(t, sg)
+	assertContains(t, squeeze(source), "Address Address `json:\"address\"`", "type Address struct")
+	compile(t, map[string]string{"models.go": source})
+}
+
+func TestRequiredReadOnlyWarning(t *testing.T) {
+	sg := generate(t, NewSchemaGen(), "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: string, readOnly: true}
+        name: {type: string}
+        created: {type: string, readOnly: true}
+`)
+	assertWarning(t, sg, "id", "both required and readOnly")
+	if len(sg.Diagnostics.Warnings) != 1 {
+		t.Errorf("expected a single warning, got %v", sg.Diagnostics.Warnings)
+	}
+	assertContains(t, squeeze(render(t, sg)), "ID string `json:\"id\"`")
+}
+
+func TestGenerateInNameOrder(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		sg := generate(t, NewSchemaGen(), "models.yaml", `
+components:
+  schemas:
+    Zebra: {type: string, x-foo: 1}
+    Alpha: {type: string, x-foo: 1}
+    Middle: {type: string, x-foo: 1}
+    Beta: {type: string, x-foo: 1}
+`)
+		var fields []string
+		for _, w := range sg.Diagnostics.Warnings {
+			fields = append(fields, w.Field)
+		}
+		if expected := []string{"Alpha", "Beta", "Middle", "Zebra"}; !reflect.DeepEqual(fields, expected) {
+			t.Fatalf("expected the warnings of %v in order, got %v", expected, fields)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package gen
+
+import "strings"
+
+// declareTextMethods declares the MarshalText and UnmarshalText methods of the named string, boolean, number and
+// time types. UnmarshalText of an enum rejects the values it does not list. The boolean and number types get JSON
+// methods as well, as encoding/json would otherwise encode them as strings through their text methods.
+func (r *renderer) declareTextMethods() {
+	for i := range r.types {
+		rt := &r.types[i]
+		u := rt.Underlying
+		kind := textKind(u)
+		if len(rt.Fields) > 0 || kind == "" {
+			continue
+		}
+		r.schema = rt.Schema
+		var marshal, parse []string
+		switch kind {
+		case "string":
+			marshal = []string{"return []byte(v), nil"}
+			parse = []string{"x := " + rt.Name + "(text)"}
+		case "bool":
+			r.addImport("strconv")
+			marshal = []string{"return strconv.AppendBool(nil, bool(v)), nil"}
+			parse = []string{"b, err := strconv.ParseBool(string(text))", "if err != nil {", "return err", "}",
+				"x := " + rt.Name + "(b)"}
+		case "int":
+			r.addImport("strconv")
+			marshal = []string{"return strconv.AppendInt(nil, int64(v), 10), nil"}
+			parse = []string{"n, err := strconv.ParseInt(string(text), 10, " + bitSize(u) + ")", "if err != nil {", "return err", "}",
+				"x := " + rt.Name + "(n)"}
+		case "uint":
+			r.addImport("strconv")
+			marshal = []string{"return strconv.AppendUint(nil, uint64(v), 10), nil"}
+			parse = []string{"n, err := strconv.ParseUint(string(text), 10, " + bitSize(u) + ")", "if err != nil {", "return err", "}",
+				"x := " + rt.Name + "(n)"}
+		case "float":
+			r.addImport("strconv")
+			marshal = []string{"return strconv.AppendFloat(nil, float64(v), 'g', -1, " + bitSize(u) + "), nil"}
+			parse = []string{"f, err := strconv.ParseFloat(string(text), " + bitSize(u) + ")", "if err != nil {", "return err", "}",
+				"x := " + rt.Name + "(f)"}
+		case "time":
+			marshal = []string{"return time.Time(v).MarshalText()"}
+			parse = []string{"var t time.Time", "if err := t.UnmarshalText(text); err != nil {", "return err", "}",
+				"x := " + rt.Name + "(t)"}
+		}
+		var values []string
+		for _, c := range rt.Consts {
+			if c.Type == "" {
+				values = append(values, c.Name)
+			}
+		}
+		if len(values) > 0 {
+			r.addImport("fmt")
+			parse = append(parse, "switch x {", "case "+strings.Join(values, ", ")+":", "*v = x", "return nil", "}",
+				"return fmt.Errorf(\"invalid "+rt.Name+" %q\", text)")
+		} else {
+			parse = append(parse, "*v = x", "return nil")
+		}
+		rt.Methods = append(rt.Methods,
+			renderMethod{
+				Doc:       []string{"MarshalText encodes the " + rt.Name + " as text."},
+				Signature: "(v " + rt.Name + ") MarshalText() ([]byte, error)",
+				Body:      marshal,
+			},
+			renderMethod{
+				Doc:       []string{"UnmarshalText decodes the " + rt.Name + " from text" + validates(values) + "."},
+				Signature: "(v *" + rt.Name + ") UnmarshalText(text []byte) error",
+				Body:      parse,
+			})
+		if kind == "string" || kind == "time" {
+			continue
+		}
+		r.addImport("encoding/json")
+		unmarshal := []string{"if string(b) == \"null\" {", "return nil", "}", "var x " + u,
+			"if err := json.Unmarshal(b, &x); err != nil {", "return err", "}"}
+		if len(values) > 0 {
+			unmarshal = append(unmarshal, "text, _ := "+rt.Name+"(x).MarshalText()", "return v.UnmarshalText(text)")
+		} else {
+			unmarshal = append(unmarshal, "*v = "+rt.Name+"(x)", "return nil")
+		}
+		rt.Methods = append(rt.Methods,
+			renderMethod{
+				Doc:       []string{"MarshalJSON encodes the " + rt.Name + " as a JSON " + jsonKind(kind) + " rather than as text."},
+				Signature: "(v " + rt.Name + ") MarshalJSON() ([]byte, error)",
+				Body:      []string{"return json.Marshal(" + u + "(v))"},
+			},
+			renderMethod{
+				Doc:       []string{"UnmarshalJSON decodes the " + rt.Name + " from a JSON " + jsonKind(kind) + validates(values) + "."},
+				Signature: "(v *" + rt.Name + ") UnmarshalJSON(b []byte) error",
+				Body:      unmarshal,
+			})
+	}
+}
+
+// textKind returns the kind of the text methods of a type with the given underlying type, empty if it gets none.
+func textKind(underlying string) string {
+	switch underlying {
+	case "string", "bool":
+		return underlying
+	case "int", "int8", "int16", "int32", "int64":
+		return "int"
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return "uint"
+	case "float32", "float64":
+		return "float"
+	case "time.Time":
+		return "time"
+	}
+	return ""
+}
+
+// bitSize returns the bit size argument of strconv for the number type, 0 for int and uint.
+func bitSize(typ string) string {
+	if i := strings.IndexAny(typ, "0123456789"); i >= 0 {
+		return typ[i:]
+	}
+	return "0"
+}
+
+// jsonKind returns the JSON type of the values of a text kind.
+func jsonKind(kind string) string {
+	if kind == "bool" {
+		return "boolean"
+	}
+	return "number"
+}
+
+// validates completes the doc comment of the decoding methods of an enum.
+func validates(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return ", rejecting the values the enum does not list"
+}
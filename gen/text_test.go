@@ -0,0 +1,94 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextMethods(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.TextMethods = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    ID: {type: string, format: uuid}
+    Day: {type: string, format: date-time}
+    Count: {type: integer, format: int32}
+    Ratio: {type: number}
+    Flag: {type: boolean}
+    Level: {type: integer, enum: [1, 2]}
+    Status: {type: string, enum: [available, sold]}
+    Pet:
+      type: object
+      required: [level, status]
+      properties:
+        level: {$ref: '#/components/schemas/Level'}
+        status: {$ref: '#/components/schemas/Status'}
+        ratio: {$ref: '#/components/schemas/Ratio'}
+`)
+	source := render(t, sg)
+	assertContains(t, source, "func (v Status) MarshalText() ([]byte, error) {", "func (v *Level) UnmarshalJSON(b []byte) error {")
+	out := run(t, map[string]string{
+		"models.go": strings.Replace(source, "package models", "package main", 1),
+		"main.go": `package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+func roundTrip(v encoding.TextMarshaler, back encoding.TextUnmarshaler) {
+	text, err := v.MarshalText()
+	if err != nil {
+		panic(err)
+	}
+	if err := back.UnmarshalText(text); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s ", text)
+}
+
+func main() {
+	var id ID
+	roundTrip(ID("f47ac10b"), &id)
+	var day Day
+	roundTrip(Day(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), &day)
+	var count Count
+	roundTrip(Count(-7), &count)
+	var ratio Ratio
+	roundTrip(Ratio(0.25), &ratio)
+	var flag Flag
+	roundTrip(Flag(true), &flag)
+	var level Level
+	roundTrip(Level2, &level)
+	var status Status
+	roundTrip(StatusSold, &status)
+	fmt.Println()
+	fmt.Println(id, time.Time(day).Year(), count, ratio, flag, level, status)
+
+	fmt.Println(status.UnmarshalText([]byte("lost")), level.UnmarshalText([]byte("3")), count.UnmarshalText([]byte("x")) != nil)
+
+	b, err := json.Marshal(map[Level]Pet{Level1: {Level: Level1, Status: StatusAvailable, Ratio: 0.5}})
+	fmt.Println(string(b), err)
+	var pets map[Level]Pet
+	fmt.Println(json.Unmarshal(b, &pets), pets[Level1].Ratio)
+	var pet Pet
+	fmt.Println(json.Unmarshal([]byte(` + "`" + `{"level":3,"status":"sold"}` + "`" + `), &pet))
+	fmt.Println(json.Unmarshal([]byte(` + "`" + `{"level":1,"status":"lost"}` + "`" + `), &pet) != nil)
+}
+`,
+	})
+	want := `f47ac10b 2020-01-02T03:04:05Z -7 0.25 true 2 sold 
+f47ac10b 2020 -7 0.25 true 2 sold
+invalid Status "lost" invalid Level "3" true
+{"1":{"level":1,"ratio":0.5,"status":"available"}} <nil>
+<nil> 0.5
+invalid Level "3"
+true
+`
+	if out != want {
+		t.Errorf("expected\n%s\ngot\n%s\n%s", want, out, source)
+	}
+}
@@ -0,0 +1,156 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ValidateFile is the name of the file WriteToDir writes ValidateSchema to.
+const ValidateFile = "validate.go"
+
+// validateHelpers declare the validator the ValidateJSON methods call.
+const validateHelpers = `
+// ValidateSchema validates the JSON document doc against the JSON Schema schema. Set it to call the validation
+// library of your choice, the ValidateJSON methods fail while it is nil.
+var ValidateSchema func(schema string, doc []byte) error
+
+// validateJSON validates doc against the schema with ValidateSchema.
+func validateJSON(schema string, doc []byte) error {
+	if ValidateSchema == nil {
+		return errors.New("ValidateSchema is not set")
+	}
+	return ValidateSchema(schema, doc)
+}
+`
+
+// SchemaSuffix is appended to the name of the type of a schema to name the constant holding the schema.
+const SchemaSuffix = "Schema"
+
+// schemaData are the keywords whose values are data rather than schemas.
+var schemaData = map[string]bool{"default": true, "enum": true, "const": true, "example": true, "examples": true}
+
+// schemaNames are the keywords whose values map names to schemas.
+var schemaNames = map[string]bool{"properties": true, "patternProperties": true, "$defs": true, "definitions": true}
+
+// declareSchemas declares along with the type of each schema the constant holding the schema and the ValidateJSON
+// method validating documents against it.
+func (r *renderer) declareSchemas(names []string) error {
+	if r.declared["ValidateSchema"] {
+		return fmt.Errorf("type ValidateSchema clashes with the validator, rename it with %s", XGoName)
+	}
+	for _, name := range names {
+		si := r.sg.SchemaInfos[name]
+		v, ok := si.Fields[name]
+		if !ok {
+			continue
+		}
+		rt := r.typeNamed(fieldOf(v).Name)
+		if rt == nil || si.Schema == nil {
+			continue
+		}
+		constant := rt.Name + SchemaSuffix
+		if r.declared[constant] {
+			return fmt.Errorf("schema %s: %s of %s clashes with another declaration, rename %s with %s",
+				rt.Schema, constant, rt.Name, rt.Name, XGoName)
+		}
+		r.declared[constant] = true
+		schema, err := r.sg.schemaJSON(si)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+		rt.Methods = append(rt.Methods, renderMethod{
+			Doc:       []string{"ValidateJSON validates the JSON document b against " + constant + " with ValidateSchema."},
+			Signature: "(" + rt.Name + ") ValidateJSON(b []byte) error",
+			Body:      []string{"return validateJSON(" + constant + ", b)"},
+		})
+		rt.Consts = append(rt.Consts, renderConst{Name: constant, Type: "string",
+			Doc: []string{constant + " is the JSON Schema of " + rt.Name + "."}, Value: rawString(schema)})
+	}
+	return nil
+}
+
+// rawString returns the Go literal of s, a raw string unless s holds a back quote.
+func rawString(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// schemaJSON returns the schema as a self-contained JSON Schema. The schemas it references are copied under $defs,
+// their references rewritten to point there, and nullable is turned into a null type. The references that match
+// no registered schema are kept as they are.
+func (sg SchemaGen) schemaJSON(si *SchemaInfo) (string, error) {
+	root, err := jsonValue(si.Schema)
+	if err != nil {
+		return "", err
+	}
+	defs := make(map[string]interface{})
+	var pending []*SchemaInfo
+	var rewrite func(docPath *url.URL, v interface{})
+	rewrite = func(docPath *url.URL, v interface{}) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := t["$ref"].(string); ok && docPath != nil {
+				if target := sg.lookupRef(docPath, ref); target == si {
+					t["$ref"] = "#"
+				} else if target != nil {
+					t["$ref"] = "#/$defs/" + target.Name
+					if _, ok := defs[target.Name]; !ok {
+						defs[target.Name] = nil
+						pending = append(pending, target)
+					}
+				}
+			}
+			if typ, ok := t["type"].(string); ok && t["nullable"] == true {
+				t["type"] = []interface{}{typ, "null"}
+				delete(t, "nullable")
+			}
+			for k, e := range t {
+				if schemaNames[k] {
+					// Keyed by names, which may well be keywords
+					m, _ := e.(map[string]interface{})
+					for _, schema := range m {
+						rewrite(docPath, schema)
+					}
+				} else if !schemaData[k] {
+					rewrite(docPath, e)
+				}
+			}
+		case []interface{}:
+			for _, e := range t {
+				rewrite(docPath, e)
+			}
+		}
+	}
+	rewrite(si.DocPath, root)
+	for len(pending) > 0 {
+		target := pending[0]
+		pending = pending[1:]
+		def, err := jsonValue(target.Schema)
+		if err != nil {
+			return "", err
+		}
+		rewrite(target.DocPath, def)
+		defs[target.Name] = def
+	}
+	if len(defs) > 0 {
+		root.(map[string]interface{})["$defs"] = defs
+	}
+	b, err := json.MarshalIndent(root, "", "  ")
+	return string(b), err
+}
+
+// jsonValue returns v decoded from its JSON encoding, as maps and slices.
+func jsonValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(b, &value)
+	return value, err
+}
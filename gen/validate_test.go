@@ -0,0 +1,162 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbedSchemas(t *testing.T) {
+	sg := NewSchemaGen()
+	sg.EmbedSchemas = true
+	sg = generate(t, sg, "pets.yaml", `
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name, status]
+      properties:
+        name: {type: string, nullable: true}
+        status: {$ref: '#/components/schemas/Status'}
+        best: {$ref: '#/components/schemas/Pet'}
+        tags:
+          type: array
+          items: {type: string}
+        properties:
+          type: object
+          properties:
+            default: {type: integer}
+    Status: {type: string, enum: [available, sold]}
+    Names:
+      type: array
+      items: {type: string}
+`)
+	files := writeDir(t, sg)
+	if _, ok := files[ValidateFile]; !ok {
+		t.Fatalf("expected %s, got %v", ValidateFile, files)
+	}
+	assertContains(t, files["Pet.go"], "PetSchema string = `{", `"$ref": "#/$defs/Status"`, `"$ref": "#"`,
+		"func (Pet) ValidateJSON(b []byte) error {")
+	assertContains(t, files["Names.go"], "NamesSchema string = `{", "func (Names) ValidateJSON(b []byte) error {")
+	main := make(map[string]string, len(files)+1)
+	for name, content := range files {
+		main[name] = strings.Replace(content, "package models", "package main", 1)
+	}
+	// A validator of the few keywords used by the schemas stands for a validation library
+	main["main.go"] = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func validate(root, schema map[string]interface{}, v interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		if ref == "#" {
+			return validate(root, root, v, path)
+		}
+		defs := root["$defs"].(map[string]interface{})
+		return validate(root, defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{}), v, path)
+	}
+	if types, ok := schema["type"]; ok {
+		var allowed []interface{}
+		if list, ok := types.([]interface{}); ok {
+			allowed = list
+		} else {
+			allowed = []interface{}{types}
+		}
+		var matched bool
+		for _, t := range allowed {
+			switch v.(type) {
+			case nil:
+				matched = matched || t == "null"
+			case string:
+				matched = matched || t == "string"
+			case float64:
+				matched = matched || t == "integer" || t == "number"
+			case []interface{}:
+				matched = matched || t == "array"
+			case map[string]interface{}:
+				matched = matched || t == "object"
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not of type %v", path, v, types)
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		var listed bool
+		for _, e := range enum {
+			listed = listed || e == v
+		}
+		if !listed {
+			return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+		}
+	}
+	if object, ok := v.(map[string]interface{}); ok {
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing %s", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, value := range object {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				if err := validate(root, property, value, path+"/"+name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if array, ok := v.([]interface{}); ok {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range array {
+				if err := validate(root, items, item, fmt.Sprint(path, "/", i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func main() {
+	fmt.Println(Pet{}.ValidateJSON([]byte("{}")))
+	ValidateSchema = func(schema string, doc []byte) error {
+		var root map[string]interface{}
+		if err := json.Unmarshal([]byte(schema), &root); err != nil {
+			return err
+		}
+		var v interface{}
+		if err := json.Unmarshal(doc, &v); err != nil {
+			return err
+		}
+		return validate(root, root, v, "")
+	}
+	for _, doc := range []string{
+		` + "`" + `{"name": null, "status": "sold", "best": {"name": "rex", "status": "available"}, "tags": ["a"]}` + "`" + `,
+		` + "`" + `{"name": "fido"}` + "`" + `,
+		` + "`" + `{"name": "fido", "status": "lost"}` + "`" + `,
+		` + "`" + `{"name": "fido", "status": "sold", "best": {"name": 1, "status": "sold"}}` + "`" + `,
+		` + "`" + `{"name": "fido", "status": "sold", "properties": {"default": "x"}}` + "`" + `,
+	} {
+		fmt.Println(Pet{}.ValidateJSON([]byte(doc)))
+	}
+	fmt.Println(Status("").ValidateJSON([]byte(` + "`" + `"sold"` + "`" + `)))
+	fmt.Println(Names{}.ValidateJSON([]byte(` + "`" + `["a", 1]` + "`" + `)))
+}
+`
+	want := `ValidateSchema is not set
+<nil>
+: missing status
+/status: lost is not one of [available sold]
+/best/name: 1 is not of type [string null]
+/properties/default: x is not of type integer
+<nil>
+/1: 1 is not of type string
+`
+	if out := run(t, main); out != want {
+		t.Errorf("expected\n%s\ngot\n%s\n%s", want, out, files["Pet.go"])
+	}
+}
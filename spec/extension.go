@@ -0,0 +1,99 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExtensionPrefix is the prefix of all specification extension keys as per https://spec.openapis.org/oas/v3.1.0#specification-extensions
+const ExtensionPrefix = "x-"
+
+// Extension returns the value of the specification extension with the given key.
+func (se SpecExtension) Extension(key string) (interface{}, bool) {
+	if se == nil {
+		return nil, false
+	}
+	v, ok := se[key]
+	return v, ok
+}
+
+// UnmarshalJSON decodes the schema and collects all the x- prefixed keys into SpecExtension. A list of types, as
+// allowed by OAS 3.1, sets Nullable when it holds "null" and sets Type when it holds a single other type.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type schemaAlias Schema
+	aux := struct {
+		*schemaAlias
+		Type interface{} `json:"type,omitempty"`
+	}{schemaAlias: (*schemaAlias)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	switch t := aux.Type.(type) {
+	case string:
+		s.Type = t
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("type %v is not a string", v)
+			}
+			if strings.EqualFold(name, "null") {
+				s.Nullable = true
+			} else {
+				types = append(types, name)
+			}
+		}
+		if len(types) == 1 {
+			s.Type = types[0]
+		}
+	case nil:
+	default:
+		return fmt.Errorf("type %v is neither a string nor a list of strings", t)
+	}
+	raw := make(map[string]interface{})
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, v := range raw {
+		if strings.HasPrefix(k, ExtensionPrefix) {
+			if s.SpecExtension == nil {
+				s.SpecExtension = make(SpecExtension)
+			}
+			s.SpecExtension[k] = v
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the schema along with the keys of SpecExtension. The discriminator and the external docs
+// are omitted when they are empty.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type schemaAlias Schema
+	b, err := json.Marshal(schemaAlias(s))
+	if err != nil {
+		return nil, err
+	}
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	if s.Discriminator.PropertyName == "" && len(s.Discriminator.Mapping) == 0 {
+		delete(raw, "discriminator")
+	}
+	if s.ExternalDocs == (ExternalDocumentation{}) {
+		delete(raw, "externalDocs")
+	}
+	for k, v := range s.SpecExtension {
+		if !strings.HasPrefix(k, ExtensionPrefix) {
+			continue
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		raw[k] = value
+	}
+	return json.Marshal(raw)
+}
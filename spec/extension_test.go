@@ -0,0 +1,78 @@
+package spec
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaExtensionsRoundTrip(t *testing.T) {
+	in := `{"type":"string","x-go-name":"Label","x-go-omitempty":false}`
+	var s Schema
+	if err := json.Unmarshal([]byte(in), &s); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Schema
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := out.Extension("x-go-name"); v != "Label" {
+		t.Errorf("x-go-name lost in %s", b)
+	}
+	if v, ok := out.Extension("x-go-omitempty"); !ok || v != false {
+		t.Errorf("x-go-omitempty lost in %s", b)
+	}
+}
+
+func TestSchemaTypeList(t *testing.T) {
+	tests := []struct {
+		in       string
+		typ      string
+		nullable bool
+	}{
+		{`{"type": "string"}`, "string", false},
+		{`{"type": ["string", "null"]}`, "string", true},
+		{`{"type": ["null", "integer"]}`, "integer", true},
+		{`{"type": ["string"]}`, "string", false},
+		{`{"type": ["string", "integer"]}`, "", false},
+		{`{"type": ["string", "integer", "null"]}`, "", true},
+		{`{}`, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var s Schema
+			if err := json.Unmarshal([]byte(tt.in), &s); err != nil {
+				t.Fatal(err)
+			}
+			if s.Type != tt.typ || s.Nullable != tt.nullable {
+				t.Errorf("expected type %q nullable %t, got type %q nullable %t", tt.typ, tt.nullable, s.Type, s.Nullable)
+			}
+		})
+	}
+}
+
+func TestSchemaTypeInvalid(t *testing.T) {
+	for _, in := range []string{`{"type": 1}`, `{"type": ["string", 1]}`} {
+		var s Schema
+		if err := json.Unmarshal([]byte(in), &s); err == nil {
+			t.Errorf("expected %s to be rejected", in)
+		}
+	}
+}
+
+func TestHeaderSummaryAndExample(t *testing.T) {
+	in := `{"$ref":"#/components/headers/Rate","summary":"Rate limit","example":100}`
+	var h Header
+	if err := json.Unmarshal([]byte(in), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Ref == nil || h.Summary != "Rate limit" {
+		t.Errorf("reference lost in %+v", h)
+	}
+	if h.Example != float64(100) {
+		t.Errorf("expected example 100, got %v", h.Example)
+	}
+}
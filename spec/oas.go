@@ -191,7 +191,7 @@ type Header struct {
 	AllowReserved bool   `json:"allowReserved,omitempty" yaml:"allowReserved,omitempty"`
 
 	//Example and Examples are mutually exclusive
-	Example
+	Example  interface{}        `json:"example,omitempty" yaml:"example,omitempty"`
 	Examples map[string]Example `json:"examples,omitempty" yaml:"examples,omitempty"`
 
 	//Schema and content Type are mutually exclusive
@@ -331,7 +331,8 @@ type Schema struct {
 	Example              interface{}           `json:"example,omitempty" yaml:"example,omitempty"`
 	Examples             []interface{}         `json:"examples,omitempty" yaml:"examples,omitempty"`
 	Deprecated           bool                  `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
-	SpecExtension
+	Comment              string                `json:"$comment,omitempty" yaml:"$comment,omitempty"`
+	SpecExtension        `json:"-" yaml:"-"`
 }
 
 type Xml struct {
@@ -345,6 +346,6 @@ type Xml struct {
 type PatternProperties map[string]*Schema
 
 type Discriminator struct {
-	PropertyName string            `json:"propertyName,omitempty" yaml:"wrapped,omitempty"`
-	mapping      map[string]string `json:"mapping,omitempty" yaml:"mapping,omitempty"`
+	PropertyName string            `json:"propertyName,omitempty" yaml:"propertyName,omitempty"`
+	Mapping      map[string]string `json:"mapping,omitempty" yaml:"mapping,omitempty"`
 }